@@ -1,6 +1,8 @@
 package uuid
 
 import (
+	"crypto/md5"
+
 	"github.com/google/uuid"
 )
 
@@ -17,3 +19,14 @@ func UUIDFromString(s string) (UUID, error) {
 func GenerateUUID() UUID {
 	return uuid.New()
 }
+
+// OfflineUUID returns the UUID vanilla servers give a player in offline mode,
+// a version 3 UUID of "OfflinePlayer:"+name. It is the same on every connect.
+func OfflineUUID(name string) UUID {
+	var id UUID
+	hash := md5.Sum([]byte("OfflinePlayer:" + name))
+	copy(id[:], hash[:])
+	id[6] = (id[6] & 0x0f) | 0x30 // version 3
+	id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return id
+}
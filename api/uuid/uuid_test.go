@@ -0,0 +1,15 @@
+package uuid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineUUID(t *testing.T) {
+	id := OfflineUUID("Notch")
+	assert.Equal(t, "b50ad385-829d-3141-a216-7e7d7539ba7f", id.String())
+	assert.Equal(t, 3, int(id.Version()))
+	assert.Equal(t, id, OfflineUUID("Notch"))
+	assert.NotEqual(t, id, OfflineUUID("notch"))
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// SharedSecretLength is the length of the shared secret, which is both the
+// AES key and the IV, so it must be one AES block.
+const SharedSecretLength = aes.BlockSize
+
+var ErrInvalidSharedSecret = errors.New("shared secret must be 16 bytes")
+
+// The standard library only ships full-block CFB, but the protocol encrypts
+// the connection with AES in 8-bit CFB mode, using the shared secret as both
+// the key and the IV.
+
+type cfb8 struct {
+	block   cipher.Block
+	iv      []byte
+	tmp     []byte
+	decrypt bool
+}
+
+func NewCFB8Encrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, false)
+}
+
+func NewCFB8Decrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, true)
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) cipher.Stream {
+	if len(iv) != block.BlockSize() {
+		panic("cfb8: IV length must equal block size")
+	}
+	return &cfb8{
+		block:   block,
+		iv:      append([]byte(nil), iv...),
+		tmp:     make([]byte, block.BlockSize()),
+		decrypt: decrypt,
+	}
+}
+
+func (c *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("cfb8: output smaller than input")
+	}
+	for i, in := range src {
+		c.block.Encrypt(c.tmp, c.iv)
+		out := in ^ c.tmp[0]
+		dst[i] = out
+
+		// shift the ciphertext byte into the register
+		copy(c.iv, c.iv[1:])
+		if c.decrypt {
+			c.iv[len(c.iv)-1] = in
+		} else {
+			c.iv[len(c.iv)-1] = out
+		}
+	}
+}
+
+// NewStreams creates the encrypt and decrypt streams for a connection from
+// the shared secret the client sent in the Encryption Response. AES accepts
+// longer keys, but the secret doubles as the IV, so anything other than
+// SharedSecretLength bytes is rejected.
+func NewStreams(sharedSecret []byte) (encrypt cipher.Stream, decrypt cipher.Stream, err error) {
+	if len(sharedSecret) != SharedSecretLength {
+		return nil, nil, ErrInvalidSharedSecret
+	}
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewCFB8Encrypter(block, sharedSecret), NewCFB8Decrypter(block, sharedSecret), nil
+}
+
+// GenerateVerifyToken returns the random token sent in the Encryption
+// Request, which the client must echo back encrypted with our public key.
+func GenerateVerifyToken() ([]byte, error) {
+	token := make([]byte, 4)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// NIST SP 800-38A, F.3.7 CFB8-AES128.Encrypt
+func TestCFB8KnownVector(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	expected, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+
+	block, err := aes.NewCipher(key)
+	assert.Nil(t, err)
+
+	actual := make([]byte, len(plaintext))
+	NewCFB8Encrypter(block, iv).XORKeyStream(actual, plaintext)
+	assert.Equal(t, expected, actual)
+
+	decrypted := make([]byte, len(actual))
+	NewCFB8Decrypter(block, iv).XORKeyStream(decrypted, actual)
+	assert.Equal(t, plaintext, decrypted)
+}
+
+func TestStreamsRoundTrip(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	encrypt, _, err := NewStreams(secret)
+	assert.Nil(t, err)
+	_, decrypt, err := NewStreams(secret)
+	assert.Nil(t, err)
+
+	// the streams keep state between calls, so split the data unevenly
+	msg := []byte("packets are encrypted as one continuous stream")
+	data := append([]byte(nil), msg...)
+	encrypt.XORKeyStream(data[:5], data[:5])
+	encrypt.XORKeyStream(data[5:], data[5:])
+	assert.NotEqual(t, msg, data)
+
+	decrypt.XORKeyStream(data[:20], data[:20])
+	decrypt.XORKeyStream(data[20:], data[20:])
+	assert.Equal(t, msg, data)
+}
+
+func TestStreamsRejectLongSecret(t *testing.T) {
+	for _, size := range []int{0, 15, 24, 32} {
+		_, _, err := NewStreams(make([]byte, size))
+		assert.ErrorIs(t, err, ErrInvalidSharedSecret, size)
+	}
+}
+
+func TestGenerateVerifyToken(t *testing.T) {
+	token, err := GenerateVerifyToken()
+	assert.Nil(t, err)
+	assert.Len(t, token, 4)
+}
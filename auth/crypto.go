@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"errors"
 )
 
 type Crypto struct {
@@ -65,3 +66,18 @@ func (c *Crypto) Encrypt(data []byte) ([]byte, error) {
 func (c *Crypto) Decrypt(data []byte) ([]byte, error) {
 	return rsa.DecryptPKCS1v15(rand.Reader, c.privateKey, data)
 }
+
+// EncryptWithPublicKey encrypts data for the owner of publicKey, given in the
+// DER form sent in the Encryption Request. Clients use it to send the shared
+// secret.
+func EncryptWithPublicKey(publicKey []byte, data []byte) ([]byte, error) {
+	key, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return rsa.EncryptPKCS1v15(rand.Reader, rsaKey, data)
+}
@@ -16,3 +16,15 @@ func TestCrypto(t *testing.T) {
 	assert.Equal(t, 1024, crypto.privateKey.N.BitLen())
 	assert.Equal(t, crypto.privateKey.PublicKey, *crypto.publicKey)
 }
+
+func TestEncryptWithPublicKey(t *testing.T) {
+	crypto, err := NewCrypto()
+	assert.Nil(t, err)
+
+	encrypted, err := EncryptWithPublicKey(crypto.PublicKeyBytes(), []byte("shared secret"))
+	assert.Nil(t, err)
+
+	decrypted, err := crypto.Decrypt(encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("shared secret"), decrypted)
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+)
+
+const DefaultSessionServerURL = "https://sessionserver.mojang.com/session/minecraft/hasJoined"
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+type ProfileProperty struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Profile is the identity the session server vouches for.
+type Profile struct {
+	ID         uuid.UUID
+	Name       string
+	Properties []ProfileProperty
+}
+
+// SessionVerifier checks that a player joining in online mode really owns
+// the account it claims, given the server hash computed by ServerHash.
+type SessionVerifier interface {
+	Verify(ctx context.Context, username, serverHash string) (*Profile, error)
+}
+
+// ServerHash is the hash both sides send to the session server: the digest
+// of the server ID, the shared secret and the server's public key.
+func ServerHash(serverID string, sharedSecret, publicKey []byte) string {
+	return AuthDigest(serverID + string(sharedSecret) + string(publicKey))
+}
+
+// HTTPSessionVerifier asks a Mojang-compatible session server's hasJoined
+// endpoint whether the player has joined with the given hash.
+type HTTPSessionVerifier struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewSessionVerifier(baseURL string) *HTTPSessionVerifier {
+	return &HTTPSessionVerifier{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type hasJoinedResponse struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Properties []ProfileProperty `json:"properties"`
+}
+
+func (v *HTTPSessionVerifier) Verify(ctx context.Context, username, serverHash string) (*Profile, error) {
+	query := url.Values{}
+	query.Set("username", username)
+	query.Set("serverId", serverHash)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		// the player has not joined with this hash
+		return nil, ErrSessionNotFound
+	default:
+		return nil, fmt.Errorf("session server returned %s", resp.Status)
+	}
+
+	var body hasJoinedResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.UUIDFromString(body.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Profile{
+		ID:         id,
+		Name:       body.Name,
+		Properties: body.Properties,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionVerifier(t *testing.T) {
+	hash := ServerHash("", []byte("0123456789abcdef"), []byte("public key"))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("username") != "Notch" || r.URL.Query().Get("serverId") != hash {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{
+			"id": "069a79f444e94726a5befca90e38aaf5",
+			"name": "Notch",
+			"properties": [{"name": "textures", "value": "e30=", "signature": "c2ln"}]
+		}`))
+	}))
+	defer ts.Close()
+
+	verifier := NewSessionVerifier(ts.URL)
+
+	profile, err := verifier.Verify(context.Background(), "Notch", hash)
+	assert.Nil(t, err)
+	assert.Equal(t, "069a79f4-44e9-4726-a5be-fca90e38aaf5", profile.ID.String())
+	assert.Equal(t, "Notch", profile.Name)
+	assert.Equal(t, []ProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}}, profile.Properties)
+
+	_, err = verifier.Verify(context.Background(), "Notch", "wrong hash")
+	assert.Equal(t, ErrSessionNotFound, err)
+}
+
+func TestSessionVerifierServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	_, err := NewSessionVerifier(ts.URL).Verify(context.Background(), "Notch", "hash")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,224 @@
+// Package capture records the packets of a connection to a file and plays
+// them back.
+//
+// A capture file starts with a magic header followed by one record per
+// packet: the time it was seen (unix nanoseconds, 8 bytes), its direction
+// and connection state (one byte each), and the framed packet as a byte
+// array prefixed by its VarInt length. Packets are recorded in the clear,
+// after decryption.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+)
+
+// Magic is the header of every capture file.
+const Magic = "MCCAP\x00\x01\n"
+
+var ErrNotCapture = errors.New("not a capture file")
+
+type Record struct {
+	Time      time.Time
+	Direction packet.Direction
+	State     fsm.FSMState
+	// Data is the framed packet: its length, id and payload
+	Data []byte
+}
+
+// Packet parses the framed packet of the record.
+func (r Record) Packet() (*packet.Packet, error) {
+	pkt := &packet.Packet{}
+	err := pkt.UnmarshalBinary(r.Data)
+	if err != nil {
+		return nil, err
+	}
+	return pkt, nil
+}
+
+// Writer appends records to a capture. It is safe to use from the
+// goroutines reading and writing a connection at the same time.
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	err    error
+}
+
+// NewWriter writes the header to w and returns a Writer appending to it.
+func NewWriter(w io.Writer) (*Writer, error) {
+	_, err := io.WriteString(w, Magic)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// Create creates the capture file at path.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// WriteRecord appends rec. After a write fails every call returns the same
+// error.
+func (w *Writer) WriteRecord(rec Record) error {
+	buf := make([]byte, 0, 8+2+binary.MaxVarintLen32+len(rec.Data))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(rec.Time.UnixNano()))
+	buf = append(buf, byte(rec.Direction), byte(rec.State))
+	buf = binary.AppendUvarint(buf, uint64(len(rec.Data)))
+	buf = append(buf, rec.Data...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	_, w.err = w.w.Write(buf)
+	return w.err
+}
+
+// Record appends pkt as seen now.
+func (w *Writer) Record(direction packet.Direction, state fsm.FSMState, pkt *packet.Packet) error {
+	data, err := pkt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.WriteRecord(Record{
+		Time:      time.Now(),
+		Direction: direction,
+		State:     state,
+		Data:      data,
+	})
+}
+
+// Close closes the file opened by Create and returns the first write error,
+// if any.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	if w.closer != nil {
+		closeErr := w.closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	if w.err == nil {
+		w.err = os.ErrClosed
+	}
+	return err
+}
+
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the header of the capture in r.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(Magic))
+	_, err := io.ReadFull(br, header)
+	if err != nil || !bytes.Equal(header, []byte(Magic)) {
+		return nil, ErrNotCapture
+	}
+	return &Reader{r: br}, nil
+}
+
+// ReadRecord returns the next record, or io.EOF after the last one.
+func (r *Reader) ReadRecord() (Record, error) {
+	var rec Record
+
+	var header [10]byte
+	_, err := io.ReadFull(r.r, header[:])
+	if err != nil {
+		// io.EOF only when the capture ends between records
+		return rec, err
+	}
+	rec.Time = time.Unix(0, int64(binary.BigEndian.Uint64(header[:8])))
+	rec.Direction = packet.Direction(header[8])
+	rec.State = fsm.FSMState(header[9])
+
+	length, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return rec, unexpectedEOF(err)
+	}
+	// a packet plus the VarInt of its length
+	if length > uint64(packet.MaxPacketSizeInBytes)+3 {
+		return rec, fmt.Errorf("record of %d bytes: %w", length, packet.ErrPacketTooLarge)
+	}
+
+	rec.Data = make([]byte, length)
+	_, err = io.ReadFull(r.r, rec.Data)
+	if err != nil {
+		return rec, unexpectedEOF(err)
+	}
+	return rec, nil
+}
+
+// ReadAll returns the records left in the capture.
+func (r *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Replay writes the packets recorded in direction to w, in order. With
+// realtime set, it waits between packets as long as the recording did.
+func Replay(w io.Writer, records []Record, direction packet.Direction, realtime bool) error {
+	var last time.Time
+	for _, rec := range records {
+		if rec.Direction != direction {
+			continue
+		}
+
+		if realtime && !last.IsZero() {
+			time.Sleep(rec.Time.Sub(last))
+		}
+		last = rec.Time
+
+		_, err := w.Write(rec.Data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
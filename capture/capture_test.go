@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKeepAlive(t *testing.T, id int32) *packet.Packet {
+	pkt := packet.NewPacket(packet.IDClientKeepAlive)
+	assert.Nil(t, pkt.Buffer().WriteInt(id))
+	return pkt
+}
+
+func TestRoundTrip(t *testing.T) {
+	var file bytes.Buffer
+	w, err := NewWriter(&file)
+	assert.Nil(t, err)
+
+	handshake := Record{
+		Time:      time.Unix(1700000000, 123),
+		Direction: packet.Serverbound,
+		State:     fsm.FSMStateHandshake,
+		Data:      []byte{0x02, 0x00, 0x05},
+	}
+	assert.Nil(t, w.WriteRecord(handshake))
+	assert.Nil(t, w.Record(packet.Clientbound, fsm.FSMStatePlay, newKeepAlive(t, 42)))
+	assert.Nil(t, w.Close())
+
+	r, err := NewReader(&file)
+	assert.Nil(t, err)
+
+	rec, err := r.ReadRecord()
+	assert.Nil(t, err)
+	assert.True(t, handshake.Time.Equal(rec.Time))
+	assert.Equal(t, handshake.Data, rec.Data)
+	assert.Equal(t, packet.Serverbound, rec.Direction)
+
+	rec, err = r.ReadRecord()
+	assert.Nil(t, err)
+	assert.Equal(t, packet.Clientbound, rec.Direction)
+	assert.Equal(t, fsm.FSMStatePlay, rec.State)
+	pkt, err := rec.Packet()
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDClientKeepAlive, pkt.ID())
+	id, err := pkt.Buffer().ReadInt()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(42), id)
+
+	_, err = r.ReadRecord()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestTruncatedRecord(t *testing.T) {
+	var file bytes.Buffer
+	w, err := NewWriter(&file)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Record(packet.Serverbound, fsm.FSMStatePlay, newKeepAlive(t, 1)))
+
+	r, err := NewReader(bytes.NewReader(file.Bytes()[:file.Len()-1]))
+	assert.Nil(t, err)
+	_, err = r.ReadRecord()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = NewReader(bytes.NewReader([]byte("not a capture")))
+	assert.ErrorIs(t, err, ErrNotCapture)
+}
+
+func TestReplay(t *testing.T) {
+	records := []Record{
+		{Direction: packet.Serverbound, Data: []byte{1, 0}},
+		{Direction: packet.Clientbound, Data: []byte{1, 1}},
+		{Direction: packet.Serverbound, Data: []byte{1, 2}},
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, Replay(&out, records, packet.Serverbound, false))
+	assert.Equal(t, []byte{1, 0, 1, 2}, out.Bytes())
+}
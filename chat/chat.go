@@ -0,0 +1,284 @@
+// Package chat models the JSON text components used for chat messages,
+// disconnect reasons and the server list MOTD, and converts them to and
+// from legacy §-coded strings.
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Component is a piece of formatted text. Its style is inherited by the
+// components in With and Extra, which are shown after its own text.
+type Component struct {
+	Text string
+	// Translate is a translation key shown in the client's language, with
+	// With filling its %s placeholders. It replaces Text when set.
+	Translate string
+	With      []Component
+
+	Color Color
+	// nil inherits the style of the parent, false turns it off
+	Bold          *bool
+	Italic        *bool
+	Underlined    *bool
+	Strikethrough *bool
+	Obfuscated    *bool
+
+	ClickEvent *ClickEvent
+	HoverEvent *HoverEvent
+
+	Extra []Component
+}
+
+// ClickEvent is what happens when the player clicks a component.
+type ClickEvent struct {
+	Action ClickAction `json:"action"`
+	Value  string      `json:"value"`
+}
+
+type ClickAction string
+
+const (
+	OpenURL        ClickAction = "open_url"
+	RunCommand     ClickAction = "run_command"
+	SuggestCommand ClickAction = "suggest_command"
+)
+
+// HoverEvent is what is shown when the player hovers over a component.
+type HoverEvent struct {
+	Action HoverAction `json:"action"`
+	// ShowItem and ShowAchievement take the item NBT or the achievement id
+	// as the text of Value
+	Value Component `json:"value"`
+}
+
+type HoverAction string
+
+const (
+	ShowText        HoverAction = "show_text"
+	ShowItem        HoverAction = "show_item"
+	ShowAchievement HoverAction = "show_achievement"
+)
+
+// Text returns a component showing text.
+func Text(text string) Component {
+	return Component{Text: text}
+}
+
+// Translate returns a component showing the translation of key, for example
+// "chat.type.text" with the sender and the message.
+func Translate(key string, with ...Component) Component {
+	return Component{Translate: key, With: with}
+}
+
+// Bool returns a pointer to v, for the style fields of Component.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// Append returns c with extra added to its children.
+func (c Component) Append(extra ...Component) Component {
+	c.Extra = append(c.Extra[:len(c.Extra):len(c.Extra)], extra...)
+	return c
+}
+
+// PlainText returns the text of c and its children without any formatting.
+// Translations are shown as their key with the arguments filled in.
+func (c Component) PlainText() string {
+	var sb strings.Builder
+	c.walk(style{}, func(text string, _ style) {
+		sb.WriteString(text)
+	})
+	return sb.String()
+}
+
+func (c Component) String() string {
+	return c.PlainText()
+}
+
+// walk calls fn with every piece of text in c in display order, with the
+// style it is shown in.
+func (c Component) walk(parent style, fn func(text string, s style)) {
+	s := parent.with(c)
+	if c.Translate != "" {
+		c.walkTranslation(s, fn)
+	} else if c.Text != "" {
+		fn(c.Text, s)
+	}
+	for _, extra := range c.Extra {
+		extra.walk(s, fn)
+	}
+}
+
+// walkTranslation fills the %s and %1$s placeholders of the key with the
+// arguments, since the translations themselves only exist in the client.
+func (c Component) walkTranslation(s style, fn func(text string, s style)) {
+	key := c.Translate
+	next := 0
+	for {
+		i := strings.IndexByte(key, '%')
+		if i < 0 || i+1 == len(key) {
+			break
+		}
+		if i > 0 {
+			fn(key[:i], s)
+		}
+		key = key[i+1:]
+
+		if key[0] == '%' {
+			fn("%", s)
+			key = key[1:]
+			continue
+		}
+
+		digits := 0
+		for digits < len(key) && key[digits] >= '0' && key[digits] <= '9' {
+			digits++
+		}
+		var arg int
+		switch {
+		case digits > 0 && strings.HasPrefix(key[digits:], "$s"):
+			n, _ := strconv.Atoi(key[:digits])
+			arg = n - 1
+			key = key[digits+2:]
+		case key[0] == 's':
+			arg = next
+			next++
+			key = key[1:]
+		default:
+			// not a placeholder
+			fn("%", s)
+			continue
+		}
+		if arg >= 0 && arg < len(c.With) {
+			c.With[arg].walk(s, fn)
+		}
+	}
+	if key != "" {
+		fn(key, s)
+	}
+}
+
+// component is Component as it is written in JSON. Text is a pointer since
+// 1.7 clients look for "text" before "translate", so it must be left out of
+// translations.
+type component struct {
+	Text          *string     `json:"text,omitempty"`
+	Translate     string      `json:"translate,omitempty"`
+	With          []Component `json:"with,omitempty"`
+	Color         Color       `json:"color,omitempty"`
+	Bold          *bool       `json:"bold,omitempty"`
+	Italic        *bool       `json:"italic,omitempty"`
+	Underlined    *bool       `json:"underlined,omitempty"`
+	Strikethrough *bool       `json:"strikethrough,omitempty"`
+	Obfuscated    *bool       `json:"obfuscated,omitempty"`
+	ClickEvent    *ClickEvent `json:"clickEvent,omitempty"`
+	HoverEvent    *HoverEvent `json:"hoverEvent,omitempty"`
+	Extra         []Component `json:"extra,omitempty"`
+}
+
+func (c Component) MarshalJSON() ([]byte, error) {
+	out := component{
+		Translate:     c.Translate,
+		With:          c.With,
+		Color:         c.Color,
+		Bold:          c.Bold,
+		Italic:        c.Italic,
+		Underlined:    c.Underlined,
+		Strikethrough: c.Strikethrough,
+		Obfuscated:    c.Obfuscated,
+		ClickEvent:    c.ClickEvent,
+		HoverEvent:    c.HoverEvent,
+		Extra:         c.Extra,
+	}
+	if c.Translate == "" {
+		out.Text = &c.Text
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON accepts the object form as well as the shorthands clients
+// accept: a plain string, a number or a boolean, shown as text, and an array
+// whose first element is the parent of the others.
+func (c *Component) UnmarshalJSON(data []byte) error {
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) == 0 {
+		return fmt.Errorf("chat: empty component")
+	}
+
+	switch data[0] {
+	case '"':
+		*c = Component{}
+		return json.Unmarshal(data, &c.Text)
+	case 't', 'f':
+		var b bool
+		err := json.Unmarshal(data, &b)
+		if err != nil {
+			return err
+		}
+		*c = Text(strconv.FormatBool(b))
+		return nil
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// kept as written, so that 3 doesn't become 3.0
+		var n json.Number
+		err := json.Unmarshal(data, &n)
+		if err != nil {
+			return err
+		}
+		*c = Text(n.String())
+		return nil
+	case '[':
+		var list []Component
+		err := json.Unmarshal(data, &list)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("chat: empty component list")
+		}
+		*c = list[0].Append(list[1:]...)
+		return nil
+	}
+
+	var in component
+	err := json.Unmarshal(data, &in)
+	if err != nil {
+		return err
+	}
+	*c = Component{
+		Translate:     in.Translate,
+		With:          in.With,
+		Color:         in.Color,
+		Bold:          in.Bold,
+		Italic:        in.Italic,
+		Underlined:    in.Underlined,
+		Strikethrough: in.Strikethrough,
+		Obfuscated:    in.Obfuscated,
+		ClickEvent:    in.ClickEvent,
+		HoverEvent:    in.HoverEvent,
+		Extra:         in.Extra,
+	}
+	if in.Text != nil {
+		c.Text = *in.Text
+	}
+	return nil
+}
+
+// JSON encodes c the way it is sent in packets.
+func (c Component) JSON() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Parse decodes chat JSON as sent in packets.
+func Parse(s string) (Component, error) {
+	var c Component
+	err := json.Unmarshal([]byte(s), &c)
+	return c, err
+}
@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	msg := Translate("chat.type.text",
+		Component{
+			Text:       "Steve",
+			ClickEvent: &ClickEvent{Action: SuggestCommand, Value: "/msg Steve "},
+			HoverEvent: &HoverEvent{Action: ShowText, Value: Text("Steve")},
+		},
+		Text("hi"),
+	)
+	msg.Color = Gray
+
+	out, err := msg.JSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"translate":"chat.type.text","with":[`+
+		`{"text":"Steve","clickEvent":{"action":"suggest_command","value":"/msg Steve "},"hoverEvent":{"action":"show_text","value":{"text":"Steve"}}},`+
+		`{"text":"hi"}],"color":"gray"}`, out)
+
+	parsed, err := Parse(out)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, parsed)
+
+	out, err = Text("").JSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"text":""}`, out)
+}
+
+func TestParseShorthands(t *testing.T) {
+	c, err := Parse(`"plain"`)
+	assert.Nil(t, err)
+	assert.Equal(t, Text("plain"), c)
+
+	c, err = Parse(`[{"text":"a","bold":true},"b",{"text":"c","bold":false}]`)
+	assert.Nil(t, err)
+	assert.Equal(t, Component{
+		Text: "a",
+		Bold: Bool(true),
+		Extra: []Component{
+			Text("b"),
+			{Text: "c", Bold: Bool(false)},
+		},
+	}, c)
+
+	// vanilla shows numbers and booleans as text, in with arguments too
+	c, err = Parse(`{"translate":"multiplayer.player.left","with":[3,"Steve",true,-1.5]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []Component{Text("3"), Text("Steve"), Text("true"), Text("-1.5")}, c.With)
+	c, err = Parse(`42`)
+	assert.Nil(t, err)
+	assert.Equal(t, Text("42"), c)
+
+	_, err = Parse(`[]`)
+	assert.NotNil(t, err)
+	_, err = Parse(`tru`)
+	assert.NotNil(t, err)
+	_, err = Parse(`{"text":`)
+	assert.NotNil(t, err)
+}
+
+func TestPlainText(t *testing.T) {
+	c := Translate("%2$s gave %1$s %s%% of %s", Text("Alex"), Text("Steve"), Text("it"))
+	assert.Equal(t, "Steve gave Alex Alex% of Steve", c.PlainText())
+
+	c = Text("Hello, ").Append(Text("world"), Text("!"))
+	assert.Equal(t, "Hello, world!", c.String())
+}
+
+func TestLegacy(t *testing.T) {
+	c := Component{Text: "A ", Color: Gold}.Append(
+		Component{Text: "Minecraft", Bold: Bool(true)},
+		Component{Text: " Server", Color: Reset},
+		Component{Text: "!", Italic: Bool(true)},
+	)
+	assert.Equal(t, "§6A §6§lMinecraft§r Server§6§o!", c.Legacy())
+
+	assert.Equal(t, "plain", Text("plain").Legacy())
+}
+
+func TestFromLegacy(t *testing.T) {
+	c := FromLegacy("§6A §lMinecraft§r Server§x!")
+	assert.Equal(t, Text("").Append(
+		Component{Text: "A ", Color: Gold},
+		Component{Text: "Minecraft", Color: Gold, Bold: Bool(true)},
+		Text(" Server!"),
+	), c)
+	assert.Equal(t, "A Minecraft Server!", c.PlainText())
+	assert.Equal(t, "§6A §6§lMinecraft§r Server!", c.Legacy())
+
+	assert.Equal(t, Text("plain"), FromLegacy("plain"))
+	assert.Equal(t, Component{Text: "red", Color: Red}, FromLegacy("§Cred"))
+	assert.Equal(t, Text("trailing§"), FromLegacy("trailing§"))
+}
+
+func TestColorCode(t *testing.T) {
+	assert.Equal(t, byte('0'), Black.Code())
+	assert.Equal(t, byte('f'), White.Code())
+	assert.Equal(t, byte('r'), Reset.Code())
+	assert.Equal(t, byte(0), Color("pink").Code())
+}
@@ -0,0 +1,200 @@
+package chat
+
+import "strings"
+
+// Color is a named text color.
+type Color string
+
+const (
+	Black       Color = "black"
+	DarkBlue    Color = "dark_blue"
+	DarkGreen   Color = "dark_green"
+	DarkAqua    Color = "dark_aqua"
+	DarkRed     Color = "dark_red"
+	DarkPurple  Color = "dark_purple"
+	Gold        Color = "gold"
+	Gray        Color = "gray"
+	DarkGray    Color = "dark_gray"
+	Blue        Color = "blue"
+	Green       Color = "green"
+	Aqua        Color = "aqua"
+	Red         Color = "red"
+	LightPurple Color = "light_purple"
+	Yellow      Color = "yellow"
+	White       Color = "white"
+	// Reset clears the color inherited from the parent
+	Reset Color = "reset"
+)
+
+// SectionSign starts a legacy formatting code.
+const SectionSign = '§'
+
+// colorCodes are the colors in the order of their legacy code, 0 to f.
+var colorCodes = []Color{
+	Black, DarkBlue, DarkGreen, DarkAqua, DarkRed, DarkPurple, Gold, Gray,
+	DarkGray, Blue, Green, Aqua, Red, LightPurple, Yellow, White,
+}
+
+const hexDigits = "0123456789abcdef"
+
+// Code returns the legacy code of c, or 0 if it has none.
+func (c Color) Code() byte {
+	for i, color := range colorCodes {
+		if color == c {
+			return hexDigits[i]
+		}
+	}
+	if c == Reset {
+		return 'r'
+	}
+	return 0
+}
+
+// style is the formatting a piece of text is shown with, after inheriting
+// from its parents.
+type style struct {
+	color                                               Color
+	bold, italic, underlined, strikethrough, obfuscated bool
+}
+
+func (s style) with(c Component) style {
+	if c.Color != "" {
+		s.color = c.Color
+	}
+	if s.color == Reset {
+		s.color = ""
+	}
+	set := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	set(&s.bold, c.Bold)
+	set(&s.italic, c.Italic)
+	set(&s.underlined, c.Underlined)
+	set(&s.strikethrough, c.Strikethrough)
+	set(&s.obfuscated, c.Obfuscated)
+	return s
+}
+
+// codes returns the legacy codes that switch to s from plain text.
+func (s style) codes() string {
+	var sb strings.Builder
+	if code := s.color.Code(); code != 0 {
+		sb.WriteRune(SectionSign)
+		sb.WriteByte(code)
+	}
+	for _, f := range []struct {
+		on   bool
+		code byte
+	}{
+		{s.obfuscated, 'k'},
+		{s.bold, 'l'},
+		{s.strikethrough, 'm'},
+		{s.underlined, 'n'},
+		{s.italic, 'o'},
+	} {
+		if f.on {
+			sb.WriteRune(SectionSign)
+			sb.WriteByte(f.code)
+		}
+	}
+	return sb.String()
+}
+
+// Legacy returns c as a §-coded string, for places that do not take JSON
+// like the legacy server list ping. Click and hover events are lost.
+func (c Component) Legacy() string {
+	var sb strings.Builder
+	current := style{}
+	c.walk(style{}, func(text string, s style) {
+		if s != current {
+			// a color code turns formatting off, so switching to a style
+			// always starts from scratch
+			if s.color == "" && current != (style{}) {
+				sb.WriteRune(SectionSign)
+				sb.WriteByte('r')
+			}
+			sb.WriteString(s.codes())
+			current = s
+		}
+		sb.WriteString(text)
+	})
+	return sb.String()
+}
+
+// FromLegacy converts a §-coded string to a component. Unknown codes are
+// dropped, like clients do.
+func FromLegacy(s string) Component {
+	var parts []Component
+	var text strings.Builder
+	current := style{}
+
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		parts = append(parts, current.component(text.String()))
+		text.Reset()
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != SectionSign || i+1 == len(runes) {
+			text.WriteRune(runes[i])
+			continue
+		}
+		i++
+		code := strings.ToLower(string(runes[i]))
+
+		next := current
+		if j := strings.Index(hexDigits, code); j >= 0 {
+			next = style{color: colorCodes[j]}
+		} else {
+			switch code {
+			case "k":
+				next.obfuscated = true
+			case "l":
+				next.bold = true
+			case "m":
+				next.strikethrough = true
+			case "n":
+				next.underlined = true
+			case "o":
+				next.italic = true
+			case "r":
+				next = style{}
+			}
+		}
+		if next != current {
+			flush()
+			current = next
+		}
+	}
+	flush()
+
+	switch len(parts) {
+	case 0:
+		return Text("")
+	case 1:
+		return parts[0]
+	}
+	return Text("").Append(parts...)
+}
+
+// component returns text shown in s, leaving out whatever is off.
+func (s style) component(text string) Component {
+	c := Component{Text: text, Color: s.color}
+	flag := func(on bool) *bool {
+		if on {
+			return Bool(true)
+		}
+		return nil
+	}
+	c.Bold = flag(s.bold)
+	c.Italic = flag(s.italic)
+	c.Underlined = flag(s.underlined)
+	c.Strikethrough = flag(s.strikethrough)
+	c.Obfuscated = flag(s.obfuscated)
+	return c
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/auth"
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+const DefaultPort = 25565
+
+var (
+	ErrAlreadyHandshaked = errors.New("client already sent a handshake")
+)
+
+// DisconnectError is returned when the server kicks the client while it is
+// logging in.
+type DisconnectError struct {
+	// Reason is the chat JSON sent by the server
+	Reason string
+}
+
+func (e *DisconnectError) Error() string {
+	reason, err := chat.Parse(e.Reason)
+	if err != nil {
+		return "disconnected by server: " + e.Reason
+	}
+	return "disconnected by server: " + reason.PlainText()
+}
+
+// Client is a single connection to a server. A connection is used either to
+// query the status or to log in, so each Client can only do one of them.
+type Client struct {
+	// ProtocolVersion is sent in the handshake and picks the packet
+	// encoding. It can be changed before calling Status or Login.
+	ProtocolVersion int32
+
+	conn   net.Conn
+	host   string
+	port   uint16
+	reader *packet.Reader
+	writer *packet.Writer
+	state  fsm.FSM
+}
+
+// Dial connects to addr. The port defaults to 25565 when addr has none.
+func Dial(addr string) (*Client, error) {
+	return DialContext(context.Background(), addr)
+}
+
+func DialContext(ctx context.Context, addr string) (*Client, error) {
+	host, port, err := SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		ProtocolVersion: protocol.Version1_7_10,
+		conn:            conn,
+		host:            host,
+		port:            port,
+		reader:          packet.NewReader(conn),
+		writer:          packet.NewWriter(conn, packet.DefaultWriterQueueSize),
+	}, nil
+}
+
+// SplitHostPort splits addr into host and port, using DefaultPort when addr
+// has no port. An IPv6 host is returned without its brackets.
+func SplitHostPort(addr string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	var addrErr *net.AddrError
+	if errors.As(err, &addrErr) && addrErr.Err == "missing port in address" {
+		if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+			return addr[1 : len(addr)-1], DefaultPort, nil
+		}
+		return addr, DefaultPort, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, uint16(port), nil
+}
+
+func (c *Client) Close() error {
+	c.writer.Close()
+	return c.conn.Close()
+}
+
+// Send encodes codec with the id it has in the current state.
+func (c *Client) Send(codec packet.Codec) error {
+	pkt, err := protocol.Registry.Packet(c.ProtocolVersion, c.state.State(), packet.Serverbound, codec)
+	if err != nil {
+		return err
+	}
+	err = c.writer.WritePacket(pkt)
+	pkt.Release()
+	return err
+}
+
+// Receive reads the next packet from the server. Packets the registry does
+// not know are reported with packet.ErrUnknownPacket and can be skipped.
+func (c *Client) Receive() (packet.Codec, error) {
+	pkt, err := c.reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	defer pkt.Release()
+	return protocol.Registry.Lookup(c.ProtocolVersion, c.state.State(), packet.Clientbound, pkt)
+}
+
+// ReadPacket reads the next packet from the server without decoding it.
+func (c *Client) ReadPacket() (*packet.Packet, error) {
+	return c.reader.ReadPacket()
+}
+
+// WritePacket queues a packet that is already encoded.
+func (c *Client) WritePacket(pkt *packet.Packet) error {
+	return c.writer.WritePacket(pkt)
+}
+
+// watch makes blocking reads and writes fail once ctx is done.
+func (c *Client) watch(ctx context.Context) (stop func()) {
+	deadline, ok := ctx.Deadline()
+	if ok {
+		c.conn.SetDeadline(deadline)
+	}
+	stopAfter := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Now())
+	})
+	return func() {
+		stopAfter()
+		c.conn.SetDeadline(time.Time{})
+	}
+}
+
+func (c *Client) handshake(nextState protocol.NextState) error {
+	if c.state.State() != fsm.FSMStateHandshake {
+		return ErrAlreadyHandshaked
+	}
+
+	err := c.Send(&protocol.HandshakePacket{
+		ProtocolVersion: c.ProtocolVersion,
+		Addr:            c.host,
+		Port:            c.port,
+		NextState:       nextState,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch nextState {
+	case protocol.HandshakeNextStateStatus:
+		c.state.SetState(fsm.FSMStateStatus)
+	case protocol.HandshakeNextStateLogin:
+		c.state.SetState(fsm.FSMStateLogin)
+	}
+	return nil
+}
+
+// receiveExpected reads packets until one is not unknown and checks that it
+// has the type T.
+func receiveExpected[T packet.Codec](c *Client) (T, error) {
+	var zero T
+	for {
+		codec, err := c.Receive()
+		if errors.Is(err, packet.ErrUnknownPacket) {
+			continue
+		}
+		if err != nil {
+			return zero, err
+		}
+
+		expected, ok := codec.(T)
+		if !ok {
+			return zero, fmt.Errorf("expected %T, got %T", zero, codec)
+		}
+		return expected, nil
+	}
+}
+
+type Status struct {
+	protocol.StatusResponse
+	// Latency is the round trip time of the ping
+	Latency time.Duration
+}
+
+// Status queries the server list information and measures the latency.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	defer c.watch(ctx)()
+
+	err := c.handshake(protocol.HandshakeNextStateStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Send(&protocol.StatusRequestPacket{})
+	if err != nil {
+		return nil, err
+	}
+	statusResponsePkt, err := receiveExpected[*protocol.StatusResponsePacket](c)
+	if err != nil {
+		return nil, err
+	}
+	statusResponse, err := statusResponsePkt.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	sentAt := time.Now()
+	payload := sentAt.UnixMilli()
+	err = c.Send(&protocol.PingRequestPacket{Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	pong, err := receiveExpected[*protocol.PingResponsePacket](c)
+	if err != nil {
+		return nil, err
+	}
+	latency := time.Since(sentAt)
+	if pong.Payload != payload {
+		return nil, fmt.Errorf("ping payload mismatch: sent %d, got %d", payload, pong.Payload)
+	}
+
+	return &Status{
+		StatusResponse: *statusResponse,
+		Latency:        latency,
+	}, nil
+}
+
+type LoginResult struct {
+	UUID uuid.UUID
+	Name string
+}
+
+// Login logs in as name. If the server asks for encryption, the connection
+// is encrypted, but no session is joined with the session server, so servers
+// verifying sessions against Mojang will reject the client.
+//
+// After Login returns the client is in the play state, and Send and Receive
+// are used to talk to the server. The caller is expected to answer the
+// server's keep alive packets.
+func (c *Client) Login(name string) (*LoginResult, error) {
+	return c.LoginContext(context.Background(), name)
+}
+
+func (c *Client) LoginContext(ctx context.Context, name string) (*LoginResult, error) {
+	defer c.watch(ctx)()
+
+	err := c.handshake(protocol.HandshakeNextStateLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Send(&protocol.LoginStartPacket{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		codec, err := c.Receive()
+		if errors.Is(err, packet.ErrUnknownPacket) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch pkt := codec.(type) {
+		case *protocol.LoginDisconnectPacket:
+			return nil, &DisconnectError{Reason: pkt.Reason}
+		case *protocol.EncryptionRequestPacket:
+			err := c.enableEncryption(pkt)
+			if err != nil {
+				return nil, err
+			}
+		case *protocol.LoginSuccessPacket:
+			id, err := uuid.UUIDFromString(pkt.UUID)
+			if err != nil {
+				return nil, err
+			}
+			c.state.SetState(fsm.FSMStatePlay)
+			return &LoginResult{
+				UUID: id,
+				Name: pkt.Username,
+			}, nil
+		}
+	}
+}
+
+func (c *Client) enableEncryption(request *protocol.EncryptionRequestPacket) error {
+	sharedSecret := make([]byte, auth.SharedSecretLength)
+	_, err := rand.Read(sharedSecret)
+	if err != nil {
+		return err
+	}
+
+	encryptedSecret, err := auth.EncryptWithPublicKey(request.PublicKey, sharedSecret)
+	if err != nil {
+		return err
+	}
+	encryptedToken, err := auth.EncryptWithPublicKey(request.PublicKey, request.VerifyToken)
+	if err != nil {
+		return err
+	}
+
+	err = c.Send(&protocol.EncryptionResponsePacket{
+		SharedSecret: encryptedSecret,
+		VerifyToken:  encryptedToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	encrypt, decrypt, err := auth.NewStreams(sharedSecret)
+	if err != nil {
+		return err
+	}
+	err = c.writer.EnableEncryption(encrypt)
+	if err != nil {
+		return err
+	}
+	c.reader.EnableEncryption(decrypt)
+	return nil
+}
@@ -0,0 +1,92 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/client"
+	"github.com/jnaraujo/mcprotocol/internal/mctest"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	addr := mctest.StartServer(t)
+	for version := range protocol.SupportedVersions {
+		c := mctest.Dial(t, addr)
+		c.ProtocolVersion = version
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		status, err := c.Status(ctx)
+		cancel()
+		assert.Nil(t, err)
+		assert.Equal(t, int(version), status.Version.Protocol)
+		assert.Equal(t, "Hello, world!", status.Description.Text)
+		assert.Equal(t, 20, status.Players.Max)
+		assert.Greater(t, status.Latency, time.Duration(0))
+
+		_, err = c.Status(context.Background())
+		assert.Equal(t, client.ErrAlreadyHandshaked, err)
+		c.Close()
+	}
+}
+
+func TestLogin(t *testing.T) {
+	c := mctest.Dial(t, mctest.StartServer(t, server.WithOfflineUUIDs(true)))
+	defer c.Close()
+
+	result, err := c.Login("Notch")
+	assert.Nil(t, err)
+	assert.Equal(t, "Notch", result.Name)
+	assert.Equal(t, uuid.OfflineUUID("Notch"), result.UUID)
+
+	joinGame := mctest.Receive[*protocol.JoinGamePacket](t, c)
+	assert.Equal(t, "default", joinGame.LevelType)
+}
+
+func TestLoginEncrypted(t *testing.T) {
+	addr := mctest.StartServer(t, server.WithOnlineMode(true), server.WithSessionVerifier(mctest.SessionVerifier{}))
+	for version := range protocol.SupportedVersions {
+		c := mctest.Dial(t, addr)
+		c.ProtocolVersion = version
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		result, err := c.LoginContext(ctx, "jeb_")
+		cancel()
+		assert.Nil(t, err)
+		assert.Equal(t, uuid.OfflineUUID("jeb_"), result.UUID)
+
+		mctest.Receive[*protocol.JoinGamePacket](t, c)
+		c.Close()
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	host, port, err := client.SplitHostPort("example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, uint16(client.DefaultPort), port)
+
+	host, port, err = client.SplitHostPort("127.0.0.1:25566")
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+	assert.Equal(t, uint16(25566), port)
+
+	host, port, err = client.SplitHostPort("[::1]")
+	assert.Nil(t, err)
+	assert.Equal(t, "::1", host)
+	assert.Equal(t, uint16(client.DefaultPort), port)
+
+	host, port, err = client.SplitHostPort("[::1]:25566")
+	assert.Nil(t, err)
+	assert.Equal(t, "::1", host)
+	assert.Equal(t, uint16(25566), port)
+
+	for _, addr := range []string{"127.0.0.1:notaport", "example.com:", "a:b:c", "[::1"} {
+		_, _, err = client.SplitHostPort(addr)
+		assert.NotNil(t, err, addr)
+	}
+}
@@ -0,0 +1,218 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+)
+
+// Argument parses one argument of a command.
+type Argument interface {
+	// Name is what the value is looked up by in the Context.
+	Name() string
+	// Usage is how the argument is shown in usage messages.
+	Usage() string
+	// Words is how many words the argument takes, or 0 for every word that
+	// is left.
+	Words() int
+	// Parse turns the words of the argument into its value.
+	Parse(ctx *Context, words []string) (any, error)
+	// Complete suggests values for the word being typed, the index-th word
+	// of the argument.
+	Complete(ctx *Context, index int, prefix string) []string
+}
+
+type intArg struct {
+	name     string
+	min, max int
+}
+
+// Int is a whole number.
+func Int(name string) Argument {
+	return IntRange(name, math.MinInt32, math.MaxInt32)
+}
+
+// IntRange is a whole number from min to max.
+func IntRange(name string, min, max int) Argument {
+	return intArg{name: name, min: min, max: max}
+}
+
+func (a intArg) Name() string  { return a.name }
+func (a intArg) Usage() string { return "<" + a.name + ">" }
+func (a intArg) Words() int    { return 1 }
+
+func (a intArg) Parse(ctx *Context, words []string) (any, error) {
+	n, err := strconv.Atoi(words[0])
+	if err != nil {
+		return nil, translatedError("commands.generic.num.invalid", chat.Text(words[0]))
+	}
+	if n < a.min {
+		return nil, translatedError("commands.generic.num.tooSmall", chat.Text(words[0]), chat.Text(strconv.Itoa(a.min)))
+	}
+	if n > a.max {
+		return nil, translatedError("commands.generic.num.tooBig", chat.Text(words[0]), chat.Text(strconv.Itoa(a.max)))
+	}
+	return n, nil
+}
+
+func (a intArg) Complete(ctx *Context, index int, prefix string) []string {
+	return nil
+}
+
+type doubleArg struct {
+	name string
+}
+
+// Double is a number that may have a fraction.
+func Double(name string) Argument {
+	return doubleArg{name: name}
+}
+
+func (a doubleArg) Name() string  { return a.name }
+func (a doubleArg) Usage() string { return "<" + a.name + ">" }
+func (a doubleArg) Words() int    { return 1 }
+
+func (a doubleArg) Parse(ctx *Context, words []string) (any, error) {
+	return parseDouble(words[0])
+}
+
+func (a doubleArg) Complete(ctx *Context, index int, prefix string) []string {
+	return nil
+}
+
+func parseDouble(word string) (float64, error) {
+	f, err := strconv.ParseFloat(word, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, translatedError("commands.generic.num.invalid", chat.Text(word))
+	}
+	return f, nil
+}
+
+type stringArg struct {
+	name   string
+	greedy bool
+}
+
+// String is a single word.
+func String(name string) Argument {
+	return stringArg{name: name}
+}
+
+// Text takes the rest of the line, so it must be the last argument.
+func Text(name string) Argument {
+	return stringArg{name: name, greedy: true}
+}
+
+func (a stringArg) Name() string { return a.name }
+
+func (a stringArg) Usage() string {
+	if a.greedy {
+		return "<" + a.name + "...>"
+	}
+	return "<" + a.name + ">"
+}
+
+func (a stringArg) Words() int {
+	if a.greedy {
+		return 0
+	}
+	return 1
+}
+
+func (a stringArg) Parse(ctx *Context, words []string) (any, error) {
+	return strings.Join(words, " "), nil
+}
+
+func (a stringArg) Complete(ctx *Context, index int, prefix string) []string {
+	return nil
+}
+
+type playerArg struct {
+	name string
+}
+
+// Player is the name of a player that is online. Its value is the Sender
+// of the player.
+func Player(name string) Argument {
+	return playerArg{name: name}
+}
+
+func (a playerArg) Name() string  { return a.name }
+func (a playerArg) Usage() string { return "<" + a.name + ">" }
+func (a playerArg) Words() int    { return 1 }
+
+func (a playerArg) Parse(ctx *Context, words []string) (any, error) {
+	for _, plr := range ctx.dispatcher.players() {
+		if strings.EqualFold(plr.Name(), words[0]) {
+			return plr, nil
+		}
+	}
+	return nil, translatedError("commands.generic.player.notFound")
+}
+
+func (a playerArg) Complete(ctx *Context, index int, prefix string) []string {
+	return ctx.dispatcher.PlayerNames(prefix)
+}
+
+type coordinatesArg struct {
+	name string
+}
+
+// Coordinates is a position given as three numbers. Each of them may be
+// relative to the sender, written as ~ followed by an optional offset.
+func Coordinates(name string) Argument {
+	return coordinatesArg{name: name}
+}
+
+func (a coordinatesArg) Name() string  { return a.name }
+func (a coordinatesArg) Usage() string { return "<x> <y> <z>" }
+func (a coordinatesArg) Words() int    { return 3 }
+
+func (a coordinatesArg) Parse(ctx *Context, words []string) (any, error) {
+	var origin Vec3
+	if positioned, ok := ctx.Sender.(Positioned); ok {
+		origin = positioned.Position()
+	}
+
+	var v Vec3
+	var err error
+	for i, c := range []struct {
+		dst    *float64
+		origin float64
+	}{
+		{&v.X, origin.X},
+		{&v.Y, origin.Y},
+		{&v.Z, origin.Z},
+	} {
+		word := words[i]
+		relative := strings.HasPrefix(word, "~")
+		if relative {
+			if _, ok := ctx.Sender.(Positioned); !ok {
+				return nil, Errorf("Relative coordinates need a position")
+			}
+			word = word[1:]
+		}
+
+		var offset float64
+		if word != "" || !relative {
+			offset, err = parseDouble(word)
+			if err != nil {
+				return nil, err
+			}
+		}
+		*c.dst = offset
+		if relative {
+			*c.dst += c.origin
+		}
+	}
+	return v, nil
+}
+
+func (a coordinatesArg) Complete(ctx *Context, index int, prefix string) []string {
+	if _, ok := ctx.Sender.(Positioned); ok && prefix == "" {
+		return []string{"~"}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package command parses and runs the commands players type in chat, and
+// suggests completions for them.
+//
+// A command declares its arguments, which are parsed before it runs:
+//
+//	d.Register(&command.Command{
+//		Name:       "tp",
+//		Permission: "minecraft.command.tp",
+//		Args:       []command.Argument{command.Player("target"), command.Coordinates("destination")},
+//		Run: func(ctx *command.Context) error {
+//			target := ctx.Player("target")
+//			destination := ctx.Coordinates("destination")
+//			...
+//		},
+//	})
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+)
+
+// Sender is who runs a command.
+type Sender interface {
+	Name() string
+	HasPermission(permission string) bool
+	SendMessage(message chat.Component) error
+}
+
+// Positioned is a Sender that is somewhere in the world, which relative
+// coordinates are measured from.
+type Positioned interface {
+	Position() Vec3
+}
+
+// Vec3 is a position in the world.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+type Command struct {
+	Name    string
+	Aliases []string
+	// Description is shown by /help
+	Description string
+	// Permission is what a sender needs to run the command. Everyone can run
+	// commands without one.
+	Permission string
+	Args       []Argument
+	Run        func(ctx *Context) error
+}
+
+// Usage returns how the command is typed, like "/tp <target> <x> <y> <z>".
+func (c *Command) Usage() string {
+	var sb strings.Builder
+	sb.WriteString("/")
+	sb.WriteString(c.Name)
+	for _, arg := range c.Args {
+		sb.WriteString(" ")
+		sb.WriteString(arg.Usage())
+	}
+	return sb.String()
+}
+
+// CanRun reports whether sender has the permission the command needs.
+func (c *Command) CanRun(sender Sender) bool {
+	return c.Permission == "" || sender.HasPermission(c.Permission)
+}
+
+// Context is a command being run.
+type Context struct {
+	Sender Sender
+	// Label is the name or alias the command was typed with
+	Label   string
+	Command *Command
+
+	dispatcher *Dispatcher
+	values     map[string]any
+}
+
+// Value returns the parsed value of the argument called name.
+func (ctx *Context) Value(name string) any {
+	return ctx.values[name]
+}
+
+func (ctx *Context) Int(name string) int {
+	v, _ := ctx.values[name].(int)
+	return v
+}
+
+func (ctx *Context) Double(name string) float64 {
+	v, _ := ctx.values[name].(float64)
+	return v
+}
+
+func (ctx *Context) String(name string) string {
+	v, _ := ctx.values[name].(string)
+	return v
+}
+
+// Player returns the player an argument made with Player names.
+func (ctx *Context) Player(name string) Sender {
+	v, _ := ctx.values[name].(Sender)
+	return v
+}
+
+func (ctx *Context) Coordinates(name string) Vec3 {
+	v, _ := ctx.values[name].(Vec3)
+	return v
+}
+
+// Reply sends message to the sender.
+func (ctx *Context) Reply(message chat.Component) error {
+	return ctx.Sender.SendMessage(message)
+}
+
+// Error is a command failing in a way the sender should be told about. Its
+// message is shown to the sender in red.
+type Error struct {
+	Message chat.Component
+}
+
+func (e *Error) Error() string {
+	return e.Message.PlainText()
+}
+
+// Errorf returns an Error showing the formatted text.
+func Errorf(format string, args ...any) error {
+	return &Error{Message: chat.Text(fmt.Sprintf(format, args...))}
+}
+
+// translatedError returns an Error showing a vanilla message, so the client
+// shows it in its own language.
+func translatedError(key string, with ...chat.Component) *Error {
+	return &Error{Message: chat.Translate(key, with...)}
+}
+
+var (
+	ErrUnknownCommand   = translatedError("commands.generic.notFound")
+	ErrNoPermission     = translatedError("commands.generic.permission")
+	ErrDuplicateCommand = errors.New("command: name or alias already registered")
+)
+
+// UsageError is returned when the arguments don't match the command.
+type UsageError struct {
+	Command *Command
+}
+
+func (e *UsageError) Error() string {
+	return "usage: " + e.Command.Usage()
+}
+
+func (e *UsageError) message() chat.Component {
+	return chat.Translate("commands.generic.usage", chat.Text(e.Command.Usage()))
+}
@@ -0,0 +1,187 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSender struct {
+	name        string
+	permissions []string
+	position    *Vec3
+	messages    []chat.Component
+}
+
+func (s *fakeSender) Name() string {
+	return s.name
+}
+
+func (s *fakeSender) HasPermission(permission string) bool {
+	for _, p := range s.permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *fakeSender) SendMessage(message chat.Component) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+type positionedSender struct {
+	*fakeSender
+}
+
+func (s positionedSender) Position() Vec3 {
+	return *s.position
+}
+
+func newDispatcher(senders ...Sender) *Dispatcher {
+	d := NewDispatcher()
+	d.Players = func() []Sender { return senders }
+	return d
+}
+
+func TestExecute(t *testing.T) {
+	steve := &fakeSender{name: "Steve"}
+	alex := &fakeSender{name: "Alex"}
+	d := newDispatcher(steve, alex)
+
+	var target Sender
+	var amount int
+	var reason string
+	err := d.Register(&Command{
+		Name:    "give",
+		Aliases: []string{"g"},
+		Args:    []Argument{Player("player"), IntRange("amount", 1, 64), Text("reason")},
+		Run: func(ctx *Context) error {
+			target = ctx.Player("player")
+			amount = ctx.Int("amount")
+			reason = ctx.String("reason")
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, d.Execute(steve, "G alex  12 for the  win"))
+	assert.Equal(t, alex, target)
+	assert.Equal(t, 12, amount)
+	assert.Equal(t, "for the win", reason)
+	assert.Empty(t, steve.messages)
+}
+
+func TestExecuteErrors(t *testing.T) {
+	steve := &fakeSender{name: "Steve"}
+	d := newDispatcher(steve)
+	d.Register(&Command{
+		Name:       "give",
+		Permission: "give",
+		Args:       []Argument{Player("player"), IntRange("amount", 1, 64)},
+		Run:        func(ctx *Context) error { return Errorf("Inventory full") },
+	})
+
+	for _, test := range []struct {
+		line    string
+		message chat.Component
+	}{
+		{"nope", chat.Translate("commands.generic.notFound")},
+		{"give Steve 1", chat.Translate("commands.generic.permission")},
+	} {
+		steve.messages = nil
+		assert.NotNil(t, d.Execute(steve, test.line))
+		test.message.Color = chat.Red
+		assert.Equal(t, []chat.Component{test.message}, steve.messages)
+	}
+
+	steve.permissions = []string{"give"}
+	for _, test := range []struct {
+		line    string
+		message chat.Component
+	}{
+		{"give", chat.Translate("commands.generic.usage", chat.Text("/give <player> <amount>"))},
+		{"give Steve 1 2", chat.Translate("commands.generic.usage", chat.Text("/give <player> <amount>"))},
+		{"give Herobrine 1", chat.Translate("commands.generic.player.notFound")},
+		{"give Steve lots", chat.Translate("commands.generic.num.invalid", chat.Text("lots"))},
+		{"give Steve 65", chat.Translate("commands.generic.num.tooBig", chat.Text("65"), chat.Text("64"))},
+		{"give Steve 1", chat.Text("Inventory full")},
+	} {
+		steve.messages = nil
+		assert.NotNil(t, d.Execute(steve, test.line), test.line)
+		test.message.Color = chat.Red
+		assert.Equal(t, []chat.Component{test.message}, steve.messages, test.line)
+	}
+}
+
+func TestCoordinates(t *testing.T) {
+	steve := positionedSender{&fakeSender{name: "Steve", position: &Vec3{X: 10, Y: 64, Z: -5}}}
+	d := newDispatcher()
+
+	var destination Vec3
+	d.Register(&Command{
+		Name: "tp",
+		Args: []Argument{Coordinates("destination"), Double("yaw")},
+		Run: func(ctx *Context) error {
+			destination = ctx.Coordinates("destination")
+			return nil
+		},
+	})
+
+	assert.Nil(t, d.Execute(steve, "tp ~ ~1.5 100 90"))
+	assert.Equal(t, Vec3{X: 10, Y: 65.5, Z: 100}, destination)
+	assert.Nil(t, d.Execute(steve, "tp -1 2 ~-5 0"))
+	assert.Equal(t, Vec3{X: -1, Y: 2, Z: -10}, destination)
+
+	assert.NotNil(t, d.Execute(steve, "tp ~x 0 0 0"))
+
+	// relative coordinates need someone to be relative to
+	notPositioned := &fakeSender{name: "Console"}
+	assert.NotNil(t, d.Execute(notPositioned, "tp ~ 0 0 0"))
+	assert.Equal(t, "Relative coordinates need a position", notPositioned.messages[0].PlainText())
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	d := NewDispatcher()
+	assert.Nil(t, d.Register(&Command{Name: "teleport", Aliases: []string{"tp"}}))
+	assert.ErrorIs(t, d.Register(&Command{Name: "TP"}), ErrDuplicateCommand)
+	assert.ErrorIs(t, d.Register(&Command{Name: "tpa", Aliases: []string{"teleport"}}), ErrDuplicateCommand)
+
+	_, ok := d.Lookup("tpa")
+	assert.False(t, ok)
+}
+
+func TestComplete(t *testing.T) {
+	steve := positionedSender{&fakeSender{name: "Steve", position: &Vec3{}}}
+	d := newDispatcher(steve, &fakeSender{name: "Sarah"}, &fakeSender{name: "Alex"})
+	d.Register(HelpCommand(d))
+	d.Register(&Command{Name: "tell", Args: []Argument{Player("player"), Text("message")}})
+	d.Register(&Command{Name: "tp", Args: []Argument{Player("player"), Coordinates("destination")}})
+	d.Register(&Command{Name: "stop", Permission: "stop"})
+
+	assert.Equal(t, []string{"tell", "tp"}, d.Complete(steve, "t"))
+	assert.Equal(t, []string{"?", "help", "tell", "tp"}, d.Complete(steve, ""))
+	assert.Empty(t, d.Complete(steve, "st"))
+	assert.Equal(t, []string{"Sarah", "Steve"}, d.Complete(steve, "tell s"))
+	assert.Equal(t, []string{"Alex", "Sarah", "Steve"}, d.Complete(steve, "tell  "))
+	assert.Empty(t, d.Complete(steve, "tell Alex hi"))
+	assert.Equal(t, []string{"~"}, d.Complete(steve, "tp Alex "))
+	assert.Equal(t, []string{"~"}, d.Complete(steve, "tp Alex 1 2 "))
+	assert.Empty(t, d.Complete(steve, "tp Alex 1 2 3 "))
+	assert.Empty(t, d.Complete(steve, "nope "))
+}
+
+func TestHelp(t *testing.T) {
+	steve := &fakeSender{name: "Steve"}
+	d := newDispatcher(steve)
+	d.Register(HelpCommand(d))
+	d.Register(&Command{Name: "stop", Permission: "stop"})
+	d.Register(&Command{Name: "tell", Description: "Whispers", Args: []Argument{Player("player"), Text("message")}})
+
+	assert.Nil(t, d.Execute(steve, "?"))
+	assert.Len(t, steve.messages, 2)
+	assert.Equal(t, "/help - Shows the commands you can use", steve.messages[0].PlainText())
+	assert.Equal(t, "/tell <player> <message...> - Whispers", steve.messages[1].PlainText())
+}
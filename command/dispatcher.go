@@ -0,0 +1,223 @@
+package command
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+)
+
+// Dispatcher holds the registered commands and runs them. It is safe to use
+// from any goroutine.
+type Dispatcher struct {
+	// Players lists the players that are online, for Player arguments.
+	Players func() []Sender
+
+	mu       sync.RWMutex
+	commands map[string]*Command
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{commands: make(map[string]*Command)}
+}
+
+// Register adds cmd under its name and aliases, which are not case
+// sensitive.
+func (d *Dispatcher) Register(cmd *Command) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	labels := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, label := range labels {
+		if _, exists := d.commands[strings.ToLower(label)]; exists {
+			return ErrDuplicateCommand
+		}
+	}
+	for _, label := range labels {
+		d.commands[strings.ToLower(label)] = cmd
+	}
+	return nil
+}
+
+// Lookup returns the command with the given name or alias.
+func (d *Dispatcher) Lookup(label string) (*Command, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	cmd, ok := d.commands[strings.ToLower(label)]
+	return cmd, ok
+}
+
+// Commands returns every registered command once, sorted by name.
+func (d *Dispatcher) Commands() []*Command {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var cmds []*Command
+	for _, cmd := range d.commands {
+		if !slices.Contains(cmds, cmd) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	slices.SortFunc(cmds, func(a, b *Command) int { return strings.Compare(a.Name, b.Name) })
+	return cmds
+}
+
+// Execute runs the command typed in line, without its leading slash. When
+// it fails, the sender is told why and the error is returned.
+func (d *Dispatcher) Execute(sender Sender, line string) error {
+	err := d.execute(sender, line)
+	if err == nil {
+		return nil
+	}
+
+	message := chat.Translate("commands.generic.exception")
+	var cmdErr *Error
+	var usageErr *UsageError
+	switch {
+	case errors.As(err, &cmdErr):
+		message = cmdErr.Message
+	case errors.As(err, &usageErr):
+		message = usageErr.message()
+	}
+	message.Color = chat.Red
+	sender.SendMessage(message)
+	return err
+}
+
+func (d *Dispatcher) execute(sender Sender, line string) error {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return ErrUnknownCommand
+	}
+	cmd, ok := d.Lookup(words[0])
+	if !ok {
+		return ErrUnknownCommand
+	}
+	if !cmd.CanRun(sender) {
+		return ErrNoPermission
+	}
+
+	ctx := &Context{
+		Sender:     sender,
+		Label:      words[0],
+		Command:    cmd,
+		dispatcher: d,
+		values:     make(map[string]any, len(cmd.Args)),
+	}
+	words = words[1:]
+	for _, arg := range cmd.Args {
+		n := arg.Words()
+		if n == 0 {
+			n = len(words)
+		}
+		if n == 0 || n > len(words) {
+			return &UsageError{Command: cmd}
+		}
+
+		value, err := arg.Parse(ctx, words[:n])
+		if err != nil {
+			return err
+		}
+		ctx.values[arg.Name()] = value
+		words = words[n:]
+	}
+	if len(words) > 0 {
+		return &UsageError{Command: cmd}
+	}
+
+	return cmd.Run(ctx)
+}
+
+// Complete suggests replacements for the last word of line, a command being
+// typed without its leading slash. Only commands sender can run are
+// suggested.
+func (d *Dispatcher) Complete(sender Sender, line string) []string {
+	words := strings.Split(line, " ")
+	// extra spaces between words don't count, but a trailing one starts a
+	// new, empty word
+	prefix := words[len(words)-1]
+	words = slices.DeleteFunc(words[:len(words)-1], func(w string) bool { return w == "" })
+
+	if len(words) == 0 {
+		return d.completeName(sender, prefix)
+	}
+
+	cmd, ok := d.Lookup(words[0])
+	if !ok || !cmd.CanRun(sender) {
+		return nil
+	}
+	ctx := &Context{Sender: sender, Label: words[0], Command: cmd, dispatcher: d}
+
+	// find the argument the word being typed belongs to
+	typed := len(words) - 1
+	for _, arg := range cmd.Args {
+		n := arg.Words()
+		if n == 0 || typed < n {
+			return arg.Complete(ctx, typed, prefix)
+		}
+		typed -= n
+	}
+	return nil
+}
+
+func (d *Dispatcher) completeName(sender Sender, prefix string) []string {
+	var names []string
+	d.mu.RLock()
+	for label, cmd := range d.commands {
+		if strings.HasPrefix(label, strings.ToLower(prefix)) && cmd.CanRun(sender) {
+			names = append(names, label)
+		}
+	}
+	d.mu.RUnlock()
+	slices.Sort(names)
+	return names
+}
+
+func (d *Dispatcher) players() []Sender {
+	if d.Players == nil {
+		return nil
+	}
+	return d.Players()
+}
+
+// PlayerNames returns the names of the online players starting with prefix,
+// ignoring case.
+func (d *Dispatcher) PlayerNames(prefix string) []string {
+	var names []string
+	for _, plr := range d.players() {
+		if len(plr.Name()) >= len(prefix) && strings.EqualFold(plr.Name()[:len(prefix)], prefix) {
+			names = append(names, plr.Name())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// HelpCommand returns a /help command listing the commands of d the sender
+// can run.
+func HelpCommand(d *Dispatcher) *Command {
+	return &Command{
+		Name:        "help",
+		Aliases:     []string{"?"},
+		Description: "Shows the commands you can use",
+		Run: func(ctx *Context) error {
+			for _, cmd := range d.Commands() {
+				if !cmd.CanRun(ctx.Sender) {
+					continue
+				}
+				line := chat.Text(cmd.Usage())
+				if cmd.Description != "" {
+					line = line.Append(chat.Component{Text: " - " + cmd.Description, Color: chat.Gray})
+				}
+				line.ClickEvent = &chat.ClickEvent{Action: chat.SuggestCommand, Value: "/" + cmd.Name + " "}
+				err := ctx.Reply(line)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jnaraujo/mcprotocol/capture"
+	"github.com/jnaraujo/mcprotocol/dissect"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+var states = map[string]fsm.FSMState{
+	"handshake": fsm.FSMStateHandshake,
+	"status":    fsm.FSMStateStatus,
+	"login":     fsm.FSMStateLogin,
+	"play":      fsm.FSMStatePlay,
+}
+
+var directions = map[string]packet.Direction{
+	"c2s":         packet.Serverbound,
+	"serverbound": packet.Serverbound,
+	"s2c":         packet.Clientbound,
+	"clientbound": packet.Clientbound,
+}
+
+// runDissect prints the packets of a capture file, or the hex encoded
+// packets read from stdin, one per line. It returns the exit code.
+func runDissect(args []string) int {
+	flags := flag.NewFlagSet("dissect", flag.ContinueOnError)
+	stateName := flags.String("state", "play", "state of the packets read from stdin: handshake, status, login or play")
+	directionName := flags.String("direction", "c2s", "who sent the packets read from stdin: c2s or s2c")
+	version := flags.Int("version", int(protocol.Version1_7_10), "protocol version of the packets read from stdin")
+	asJSON := flags.Bool("json", false, "print one JSON object per packet")
+	capturePath := flags.String("capture", "", "dissect a capture file instead of stdin")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	print := func(p *dissect.Packet) error {
+		if *asJSON {
+			return json.NewEncoder(os.Stdout).Encode(p)
+		}
+		_, err := fmt.Println(p)
+		return err
+	}
+
+	if *capturePath != "" {
+		err = dissectCapture(*capturePath, print)
+	} else {
+		state, ok := states[strings.ToLower(*stateName)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown state %q\n", *stateName)
+			return 2
+		}
+		direction, ok := directions[strings.ToLower(*directionName)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown direction %q\n", *directionName)
+			return 2
+		}
+		err = dissectHex(os.Stdin, int32(*version), state, direction, print)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func dissectCapture(path string, print func(*dissect.Packet) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := capture.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	d := dissect.NewDissector()
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = print(d.Record(rec))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dissectHex reads one packet per line. Spaces are ignored, and empty lines
+// and lines starting with # are skipped.
+func dissectHex(r io.Reader, version int32, state fsm.FSMState, direction packet.Direction, print func(*dissect.Packet) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 4*int(packet.MaxPacketSizeInBytes))
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.Join(strings.Fields(scanner.Text()), "")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		data, err := hex.DecodeString(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		err = print(dissect.Dissect(data, version, state, direction))
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,214 @@
+// Package dissect turns raw packets into something a person can read, using
+// the packet definitions of the protocol package.
+package dissect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jnaraujo/mcprotocol/capture"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Packet is a dissected packet. When the packet is unknown or fails to
+// decode, Name is empty or Err is set and Raw holds the payload.
+type Packet struct {
+	Direction packet.Direction
+	State     fsm.FSMState
+	ID        packet.PacketID
+	// Name is the Go type of the packet without the Packet suffix
+	Name   string
+	Fields []Field
+	Raw    []byte
+	Err    error
+}
+
+// Dissect decodes data, either a framed packet (length, id and payload) or
+// just its id and payload, as sent by the given side in state.
+func Dissect(data []byte, version int32, state fsm.FSMState, direction packet.Direction) *Packet {
+	p := &Packet{
+		Direction: direction,
+		State:     state,
+	}
+
+	data = unframe(data)
+	if len(data) == 0 {
+		p.Err = packet.ErrInvalidPacketLength
+		return p
+	}
+	p.ID = packet.PacketID(data[0])
+	p.Raw = data[1:]
+
+	codec, ok := protocol.Registry.New(version, state, direction, p.ID)
+	if !ok {
+		return p
+	}
+	p.Name = strings.TrimSuffix(reflect.TypeOf(codec).Elem().Name(), "Packet")
+
+	pkt := packet.NewPacketFromBuffer(raknet.NewBufferFrom(bytes.Clone(p.Raw)), p.ID)
+	codec, err := protocol.Registry.Lookup(version, state, direction, pkt)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	p.Fields = fields(reflect.ValueOf(codec).Elem())
+	p.Raw = nil
+	return p
+}
+
+// unframe strips the length prefix when data starts with one that matches
+// its length.
+func unframe(data []byte) []byte {
+	buf := raknet.NewBufferFrom(data)
+	length, err := buf.ReadVarInt()
+	if err == nil && int(length) == buf.Len() {
+		return buf.Bytes()
+	}
+	return data
+}
+
+func fields(v reflect.Value) []Field {
+	var fields []Field
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, Field{Name: field.Name, Value: v.Field(i).Interface()})
+	}
+	return fields
+}
+
+// Arrow returns C→S for packets sent by the client and S→C for packets sent
+// by the server.
+func Arrow(direction packet.Direction) string {
+	if direction == packet.Serverbound {
+		return "C→S"
+	}
+	return "S→C"
+}
+
+// String renders the packet on one line:
+//
+//	C→S PLAY PlayerPosition{X:1.5 FeetY:64 HeadY:65.62 Z:-3 OnGround:true}
+func (p *Packet) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s ", Arrow(p.Direction), p.State)
+
+	if p.Name == "" {
+		fmt.Fprintf(&sb, "%s{unknown, %d bytes: %x}", p.ID, len(p.Raw), p.Raw)
+		return sb.String()
+	}
+
+	sb.WriteString(p.Name)
+	if p.Err != nil {
+		fmt.Fprintf(&sb, "{error: %v, %d bytes: %x}", p.Err, len(p.Raw), p.Raw)
+		return sb.String()
+	}
+
+	sb.WriteByte('{')
+	for i, f := range p.Fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%s:%s", f.Name, formatValue(f.Value))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return hex.EncodeToString(v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// MarshalJSON renders the packet as an object, keeping the fields in wire
+// order.
+func (p *Packet) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"direction":`)
+	writeJSON(&buf, p.Direction.String())
+	buf.WriteString(`,"state":`)
+	writeJSON(&buf, p.State.String())
+	buf.WriteString(`,"id":`)
+	writeJSON(&buf, int(p.ID))
+	if p.Name != "" {
+		buf.WriteString(`,"name":`)
+		writeJSON(&buf, p.Name)
+	}
+	if p.Err != nil {
+		buf.WriteString(`,"error":`)
+		writeJSON(&buf, p.Err.Error())
+	}
+	if p.Fields != nil {
+		buf.WriteString(`,"fields":{`)
+		for i, f := range p.Fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(&buf, f.Name)
+			buf.WriteByte(':')
+			err := writeJSON(&buf, f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+		}
+		buf.WriteByte('}')
+	}
+	if p.Raw != nil {
+		buf.WriteString(`,"raw":`)
+		writeJSON(&buf, hex.EncodeToString(p.Raw))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// Dissector dissects the records of a capture. It starts out assuming
+// 1.7.10 and switches to the version the client sends in its handshake.
+type Dissector struct {
+	Version int32
+}
+
+func NewDissector() *Dissector {
+	return &Dissector{Version: protocol.Version1_7_10}
+}
+
+func (d *Dissector) Record(rec capture.Record) *Packet {
+	p := Dissect(rec.Data, d.Version, rec.State, rec.Direction)
+	if rec.State == fsm.FSMStateHandshake && p.Name == "Handshake" && p.Err == nil {
+		for _, f := range p.Fields {
+			if version, ok := f.Value.(int32); ok && f.Name == "ProtocolVersion" && protocol.IsSupportedVersion(version) {
+				d.Version = version
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,69 @@
+package dissect
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/capture"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func encode(t *testing.T, version int32, state fsm.FSMState, direction packet.Direction, codec packet.Codec) []byte {
+	pkt, err := protocol.Registry.Packet(version, state, direction, codec)
+	assert.Nil(t, err)
+	data, err := pkt.MarshalBinary()
+	assert.Nil(t, err)
+	return data
+}
+
+func TestDissectString(t *testing.T) {
+	data := encode(t, protocol.Version1_7_10, fsm.FSMStatePlay, packet.Serverbound, &protocol.PlayerPositionPacket{
+		X: 1.5, FeetY: 64, HeadY: 65.62, Z: -3, OnGround: true,
+	})
+
+	p := Dissect(data, protocol.Version1_7_10, fsm.FSMStatePlay, packet.Serverbound)
+	assert.Nil(t, p.Err)
+	assert.Equal(t, "C→S PLAY PlayerPosition{X:1.5 FeetY:64 HeadY:65.62 Z:-3 OnGround:true}", p.String())
+
+	// without the length prefix
+	p = Dissect(data[1:], protocol.Version1_7_10, fsm.FSMStatePlay, packet.Serverbound)
+	assert.Equal(t, "PlayerPosition", p.Name)
+
+	p = Dissect([]byte{0x10, 0x01, 0x02}, protocol.Version1_7_10, fsm.FSMStatePlay, packet.Clientbound)
+	assert.Equal(t, "S→C PLAY 0x10{unknown, 2 bytes: 0102}", p.String())
+
+	// a Join Game cut after the entity id
+	p = Dissect([]byte{0x01, 0, 0, 0, 1}, protocol.Version1_7_10, fsm.FSMStatePlay, packet.Clientbound)
+	assert.NotNil(t, p.Err)
+	assert.Equal(t, "JoinGame", p.Name)
+	assert.Equal(t, []byte{0, 0, 0, 1}, p.Raw)
+}
+
+func TestDissectJSON(t *testing.T) {
+	data := encode(t, protocol.Version1_7_10, fsm.FSMStateLogin, packet.Serverbound, &protocol.LoginStartPacket{Name: "Notch"})
+
+	out, err := json.Marshal(Dissect(data, protocol.Version1_7_10, fsm.FSMStateLogin, packet.Serverbound))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"direction":"serverbound","state":"LOGIN","id":0,"name":"LoginStart","fields":{"Name":"Notch"}}`, string(out))
+}
+
+func TestDissectorFollowsHandshakeVersion(t *testing.T) {
+	d := NewDissector()
+
+	handshake := encode(t, protocol.Version1_8, fsm.FSMStateHandshake, packet.Serverbound, &protocol.HandshakePacket{
+		ProtocolVersion: protocol.Version1_8,
+		Addr:            "localhost",
+		Port:            25565,
+		NextState:       protocol.HandshakeNextStateLogin,
+	})
+	d.Record(capture.Record{Direction: packet.Serverbound, State: fsm.FSMStateHandshake, Data: handshake})
+	assert.Equal(t, protocol.Version1_8, d.Version)
+
+	// 1.8 keep alives have a VarInt id
+	keepAlive := encode(t, protocol.Version1_8, fsm.FSMStatePlay, packet.Clientbound, &protocol.KeepAlivePacket{ID: 7})
+	p := d.Record(capture.Record{Direction: packet.Clientbound, State: fsm.FSMStatePlay, Data: keepAlive})
+	assert.Equal(t, "S→C PLAY KeepAlive{ID:7}", p.String())
+}
@@ -1,5 +1,7 @@
 package fsm
 
+import "fmt"
+
 type FSMState uint8
 
 const (
@@ -27,3 +29,19 @@ func (fsm *FSM) SetState(state FSMState) {
 func (fsm *FSM) State() FSMState {
 	return fsm.currentState
 }
+
+func (s FSMState) String() string {
+	switch s {
+	case FSMStateHandshake:
+		return "HANDSHAKE"
+	case FSMStateStatus:
+		return "STATUS"
+	case FSMStateLogin:
+		return "LOGIN"
+	case FSMStateConfiguration:
+		return "CONFIGURATION"
+	case FSMStatePlay:
+		return "PLAY"
+	}
+	return fmt.Sprintf("FSMState(%d)", uint8(s))
+}
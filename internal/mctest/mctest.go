@@ -0,0 +1,96 @@
+// Package mctest runs servers and connects clients to them for the tests of
+// the other packages.
+package mctest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/auth"
+	"github.com/jnaraujo/mcprotocol/client"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// Timeout is how long the helpers wait for a packet before giving up.
+const Timeout = 5 * time.Second
+
+// FreeAddr returns a local address nothing listens on.
+func FreeAddr(t testing.TB) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// StartServer runs a server on a free local port and returns its address
+// once it is listening.
+func StartServer(t testing.TB, opts ...server.Option) string {
+	addr := FreeAddr(t)
+	go server.NewServer(addr, opts...).Listen()
+	Dial(t, addr).Close()
+	return addr
+}
+
+// Dial connects to addr, waiting for it to be listening.
+func Dial(t testing.TB, addr string) *client.Client {
+	for range 50 {
+		c, err := client.Dial(addr)
+		if err == nil {
+			return c
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s is not listening", addr)
+	return nil
+}
+
+// Login connects a player named name and waits until it is in the world.
+func Login(t testing.TB, addr, name string) *client.Client {
+	c := Dial(t, addr)
+	_, err := c.Login(name)
+	assert.Nil(t, err)
+	Receive[*protocol.JoinGamePacket](t, c)
+	return c
+}
+
+// Receive skips packets until one of type T arrives. When none does within
+// Timeout, c is closed and the test fails.
+func Receive[T packet.Codec](t testing.TB, c *client.Client) T {
+	stop := Watch(c)
+	defer stop()
+
+	for {
+		codec, err := c.Receive()
+		if errors.Is(err, packet.ErrUnknownPacket) {
+			continue
+		}
+		if !assert.Nil(t, err) {
+			var zero T
+			return zero
+		}
+		if expected, ok := codec.(T); ok {
+			return expected
+		}
+	}
+}
+
+// Watch closes c unless stop is called within Timeout, which makes reads
+// waiting for packets that never come fail.
+func Watch(c *client.Client) (stop func()) {
+	timer := time.AfterFunc(Timeout, func() { c.Close() })
+	return func() { timer.Stop() }
+}
+
+// SessionVerifier lets every player in, with its offline UUID.
+type SessionVerifier struct{}
+
+func (SessionVerifier) Verify(ctx context.Context, username, serverHash string) (*auth.Profile, error) {
+	return &auth.Profile{ID: uuid.OfflineUUID(username), Name: username}, nil
+}
@@ -0,0 +1,182 @@
+// Package item describes item stacks: what is in an inventory slot, dropped
+// on the ground or held by an entity.
+package item
+
+import "github.com/jnaraujo/mcprotocol/nbt"
+
+// ItemStack is a number of items of the same kind. NBT holds the extra
+// data of the stack, like its name and enchantments, and is nil when there
+// is none.
+type ItemStack struct {
+	ID    int16
+	Count byte
+	// durability used up for tools and armor, the variant for everything
+	// else, like the color of wool
+	Damage int16
+	NBT    nbt.Compound
+}
+
+// New returns a stack of count items with the given id.
+func New(id int16, count byte) *ItemStack {
+	return &ItemStack{ID: id, Count: count}
+}
+
+// Enchantment is an entry of the "ench" list of a stack.
+type Enchantment struct {
+	ID    int16 `nbt:"id"`
+	Level int16 `nbt:"lvl"`
+}
+
+// Enchantment ids in 1.7.10.
+const (
+	Protection           int16 = 0
+	FireProtection       int16 = 1
+	FeatherFalling       int16 = 2
+	BlastProtection      int16 = 3
+	ProjectileProtection int16 = 4
+	Respiration          int16 = 5
+	AquaAffinity         int16 = 6
+	Thorns               int16 = 7
+	Sharpness            int16 = 16
+	Smite                int16 = 17
+	BaneOfArthropods     int16 = 18
+	Knockback            int16 = 19
+	FireAspect           int16 = 20
+	Looting              int16 = 21
+	Efficiency           int16 = 32
+	SilkTouch            int16 = 33
+	Unbreaking           int16 = 34
+	Fortune              int16 = 35
+	Power                int16 = 48
+	Punch                int16 = 49
+	Flame                int16 = 50
+	Infinity             int16 = 51
+	LuckOfTheSea         int16 = 61
+	Lure                 int16 = 62
+)
+
+// DisplayName returns the name the stack was renamed to, if any.
+func (s *ItemStack) DisplayName() (string, bool) {
+	name, ok := s.display()["Name"].(string)
+	return name, ok
+}
+
+// SetDisplayName renames the stack. An empty name removes it.
+func (s *ItemStack) SetDisplayName(name string) {
+	if name == "" {
+		s.removeDisplay("Name")
+		return
+	}
+	s.setDisplay("Name", name)
+}
+
+// Lore returns the lines shown under the name of the stack.
+func (s *ItemStack) Lore() []string {
+	list, _ := s.display()["Lore"].([]any)
+	lore := make([]string, 0, len(list))
+	for _, line := range list {
+		if str, ok := line.(string); ok {
+			lore = append(lore, str)
+		}
+	}
+	return lore
+}
+
+// SetLore replaces the lines shown under the name of the stack. No lines
+// removes the lore.
+func (s *ItemStack) SetLore(lines ...string) {
+	if len(lines) == 0 {
+		s.removeDisplay("Lore")
+		return
+	}
+	list := make([]any, len(lines))
+	for i, line := range lines {
+		list[i] = line
+	}
+	s.setDisplay("Lore", list)
+}
+
+// Enchantments returns the enchantments on the stack.
+func (s *ItemStack) Enchantments() []Enchantment {
+	list, _ := s.NBT["ench"].([]any)
+	enchantments := make([]Enchantment, 0, len(list))
+	for _, entry := range list {
+		c, ok := entry.(nbt.Compound)
+		if !ok {
+			continue
+		}
+		id, _ := c["id"].(int16)
+		level, _ := c["lvl"].(int16)
+		enchantments = append(enchantments, Enchantment{ID: id, Level: level})
+	}
+	return enchantments
+}
+
+// Enchant adds the enchantment id at level, replacing its current level if
+// the stack already has it.
+func (s *ItemStack) Enchant(id, level int16) {
+	list := s.removeEnchantment(id)
+	list = append(list, nbt.Compound{"id": id, "lvl": level})
+	s.setTag("ench", list)
+}
+
+// RemoveEnchantment takes the enchantment id off the stack.
+func (s *ItemStack) RemoveEnchantment(id int16) {
+	list := s.removeEnchantment(id)
+	if len(list) == 0 {
+		s.removeTag("ench")
+		return
+	}
+	s.setTag("ench", list)
+}
+
+func (s *ItemStack) removeEnchantment(id int16) []any {
+	list, _ := s.NBT["ench"].([]any)
+	kept := make([]any, 0, len(list)+1)
+	for _, entry := range list {
+		if c, ok := entry.(nbt.Compound); ok && c["id"] == id {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+func (s *ItemStack) display() nbt.Compound {
+	display, _ := s.NBT["display"].(nbt.Compound)
+	return display
+}
+
+func (s *ItemStack) setDisplay(key string, value any) {
+	display := s.display()
+	if display == nil {
+		display = nbt.Compound{}
+		s.setTag("display", display)
+	}
+	display[key] = value
+}
+
+func (s *ItemStack) removeDisplay(key string) {
+	display := s.display()
+	delete(display, key)
+	if len(display) == 0 {
+		s.removeTag("display")
+	}
+}
+
+func (s *ItemStack) setTag(key string, value any) {
+	if s.NBT == nil {
+		s.NBT = nbt.Compound{}
+	}
+	s.NBT[key] = value
+}
+
+// removeTag deletes key and drops NBT altogether once it is empty, since
+// clients treat a stack with an empty compound as different from one
+// without.
+func (s *ItemStack) removeTag(key string) {
+	delete(s.NBT, key)
+	if len(s.NBT) == 0 {
+		s.NBT = nil
+	}
+}
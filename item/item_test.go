@@ -0,0 +1,66 @@
+package item
+
+import (
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/nbt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplay(t *testing.T) {
+	sword := New(276, 1)
+	_, ok := sword.DisplayName()
+	assert.False(t, ok)
+	assert.Empty(t, sword.Lore())
+
+	sword.SetDisplayName("Excalibur")
+	sword.SetLore("Pulled from", "the stone")
+	name, ok := sword.DisplayName()
+	assert.True(t, ok)
+	assert.Equal(t, "Excalibur", name)
+	assert.Equal(t, []string{"Pulled from", "the stone"}, sword.Lore())
+	assert.Equal(t, nbt.Compound{
+		"display": nbt.Compound{
+			"Name": "Excalibur",
+			"Lore": []any{"Pulled from", "the stone"},
+		},
+	}, sword.NBT)
+
+	sword.SetDisplayName("")
+	sword.SetLore()
+	assert.Nil(t, sword.NBT)
+}
+
+func TestEnchantments(t *testing.T) {
+	sword := New(276, 1)
+	sword.Enchant(Sharpness, 4)
+	sword.Enchant(Unbreaking, 3)
+	sword.Enchant(Sharpness, 5)
+	assert.Equal(t, []Enchantment{{Unbreaking, 3}, {Sharpness, 5}}, sword.Enchantments())
+
+	sword.RemoveEnchantment(Unbreaking)
+	assert.Equal(t, []Enchantment{{Sharpness, 5}}, sword.Enchantments())
+	sword.RemoveEnchantment(Sharpness)
+	assert.Empty(t, sword.Enchantments())
+	assert.Nil(t, sword.NBT)
+}
+
+func TestEnchantmentsFromNBT(t *testing.T) {
+	// the tag of an enchanted book, as written by vanilla
+	data := []byte{
+		0x0a, 0x00, 0x00,
+		0x09, 0x00, 0x04, 'e', 'n', 'c', 'h', 0x0a, 0x00, 0x00, 0x00, 0x01,
+		0x02, 0x00, 0x02, 'i', 'd', 0x00, 0x21,
+		0x02, 0x00, 0x03, 'l', 'v', 'l', 0x00, 0x01,
+		0x00,
+		0x00,
+	}
+	book := New(403, 1)
+	err := nbt.Unmarshal(data, &book.NBT)
+	assert.Nil(t, err)
+	assert.Equal(t, []Enchantment{{SilkTouch, 1}}, book.Enchantments())
+
+	out, err := nbt.Marshal(book.NBT)
+	assert.Nil(t, err)
+	assert.Equal(t, data, out)
+}
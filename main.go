@@ -1,14 +1,78 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/jnaraujo/mcprotocol/server"
 )
 
+const usage = `usage:
+  mcprotocol [--capture-dir dir]      start the server on :25565
+  mcprotocol ping <host[:port]> [--json] [--timeout 5s]
+  mcprotocol proxy <config.json> [--addr :25565]
+  mcprotocol replay <capture> [--addr localhost:25565] [--realtime] [--serve]
+  mcprotocol dissect [--state play] [--direction c2s] [--version 5] [--json] < packets.hex
+  mcprotocol dissect --capture <capture> [--json]
+`
+
 func main() {
-	sv := server.NewServer(":25565")
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runServer(os.Args[1:]))
+	}
+
+	switch os.Args[1] {
+	case "ping":
+		os.Exit(runPing(os.Args[2:]))
+	case "proxy":
+		os.Exit(runProxy(os.Args[2:]))
+	case "replay":
+		os.Exit(runReplay(os.Args[2:]))
+	case "dissect":
+		os.Exit(runDissect(os.Args[2:]))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runServer(args []string) int {
+	flags := flag.NewFlagSet("mcprotocol", flag.ContinueOnError)
+	captureDir := flags.String("capture-dir", "", "record every connection to a capture file in this directory")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	var opts []server.Option
+	if *captureDir != "" {
+		opts = append(opts, server.WithCaptureDir(*captureDir))
+	}
+	sv := server.NewServer(":25565", opts...)
 
-	err := sv.Listen()
+	err = sv.Listen()
 	if err != nil {
 		panic(err)
 	}
+	return 0
+}
+
+// parseArgs parses flags that may come before or after the positional
+// arguments, which it returns.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
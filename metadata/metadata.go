@@ -0,0 +1,309 @@
+// Package metadata builds and parses entity metadata, the indexed list of
+// typed values Spawn Player, Spawn Mob and Entity Metadata carry.
+package metadata
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jnaraujo/mcprotocol/item"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+// Indices shared by every entity.
+const (
+	// IndexFlags is a byte of Flag bits.
+	IndexFlags byte = 0
+	// IndexAir is a short of the air left while under water.
+	IndexAir byte = 1
+)
+
+// Indices shared by living entities in 1.7.10.
+const (
+	IndexHealth         byte = 6
+	IndexPotionColor    byte = 7
+	IndexPotionAmbient  byte = 8
+	IndexArrows         byte = 9
+	IndexNameTag        byte = 10
+	IndexAlwaysShowName byte = 11
+)
+
+// Flag is a bit of the byte at IndexFlags.
+type Flag byte
+
+const (
+	FlagOnFire    Flag = 0x01
+	FlagCrouched  Flag = 0x02
+	FlagSprinting Flag = 0x08
+	// FlagEating is also set while drinking, blocking or drawing a bow
+	FlagEating    Flag = 0x10
+	FlagInvisible Flag = 0x20
+)
+
+// Vector is the value of a MetadataVector entry, usually a block position.
+type Vector struct {
+	X, Y, Z int32
+}
+
+// Rotation is the value of a MetadataRotation entry, sent by 1.8 for armor
+// stands.
+type Rotation struct {
+	X, Y, Z float32
+}
+
+// Entry is a single metadata value. Value holds the Go type matching Type:
+// byte, int16, int32, float32, string, *item.ItemStack, Vector or Rotation.
+type Entry struct {
+	Type  raknet.MetadataType
+	Value any
+}
+
+// Metadata is the metadata of an entity. The zero value is empty and ready
+// to use.
+type Metadata struct {
+	entries map[byte]Entry
+}
+
+func New() *Metadata {
+	return &Metadata{}
+}
+
+func (m *Metadata) set(index byte, typ raknet.MetadataType, value any) {
+	if m.entries == nil {
+		m.entries = make(map[byte]Entry)
+	}
+	m.entries[index] = Entry{Type: typ, Value: value}
+}
+
+func (m *Metadata) SetByte(index byte, value byte) {
+	m.set(index, raknet.MetadataByte, value)
+}
+
+func (m *Metadata) SetShort(index byte, value int16) {
+	m.set(index, raknet.MetadataShort, value)
+}
+
+func (m *Metadata) SetInt(index byte, value int32) {
+	m.set(index, raknet.MetadataInt, value)
+}
+
+func (m *Metadata) SetFloat(index byte, value float32) {
+	m.set(index, raknet.MetadataFloat, value)
+}
+
+func (m *Metadata) SetString(index byte, value string) {
+	m.set(index, raknet.MetadataString, value)
+}
+
+// SetSlot sets an item, or an empty slot when stack is nil.
+func (m *Metadata) SetSlot(index byte, stack *item.ItemStack) {
+	m.set(index, raknet.MetadataSlot, stack)
+}
+
+func (m *Metadata) SetVector(index byte, value Vector) {
+	m.set(index, raknet.MetadataVector, value)
+}
+
+func (m *Metadata) SetRotation(index byte, value Rotation) {
+	m.set(index, raknet.MetadataRotation, value)
+}
+
+// Get returns the entry at index.
+func (m *Metadata) Get(index byte) (Entry, bool) {
+	entry, ok := m.entries[index]
+	return entry, ok
+}
+
+// Byte returns the value at index if it is a byte.
+func (m *Metadata) Byte(index byte) (byte, bool) {
+	v, ok := m.entries[index].Value.(byte)
+	return v, ok
+}
+
+func (m *Metadata) Short(index byte) (int16, bool) {
+	v, ok := m.entries[index].Value.(int16)
+	return v, ok
+}
+
+func (m *Metadata) Int(index byte) (int32, bool) {
+	v, ok := m.entries[index].Value.(int32)
+	return v, ok
+}
+
+func (m *Metadata) Float(index byte) (float32, bool) {
+	v, ok := m.entries[index].Value.(float32)
+	return v, ok
+}
+
+func (m *Metadata) String(index byte) (string, bool) {
+	v, ok := m.entries[index].Value.(string)
+	return v, ok
+}
+
+// Slot returns the item at index. An empty slot is a nil stack, with ok set.
+func (m *Metadata) Slot(index byte) (*item.ItemStack, bool) {
+	entry, ok := m.entries[index]
+	if !ok || entry.Type != raknet.MetadataSlot {
+		return nil, false
+	}
+	return entry.Value.(*item.ItemStack), true
+}
+
+func (m *Metadata) Vector(index byte) (Vector, bool) {
+	v, ok := m.entries[index].Value.(Vector)
+	return v, ok
+}
+
+func (m *Metadata) Rotation(index byte) (Rotation, bool) {
+	v, ok := m.entries[index].Value.(Rotation)
+	return v, ok
+}
+
+// Remove deletes the entry at index.
+func (m *Metadata) Remove(index byte) {
+	delete(m.entries, index)
+}
+
+// Len returns the number of entries.
+func (m *Metadata) Len() int {
+	return len(m.entries)
+}
+
+// Indices returns the indices that have an entry, in order.
+func (m *Metadata) Indices() []byte {
+	indices := make([]byte, 0, len(m.entries))
+	for index := range m.entries {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+	return indices
+}
+
+// Flags returns the byte at IndexFlags.
+func (m *Metadata) Flags() Flag {
+	flags, _ := m.Byte(IndexFlags)
+	return Flag(flags)
+}
+
+// HasFlag reports whether every bit of flag is set.
+func (m *Metadata) HasFlag(flag Flag) bool {
+	return m.Flags()&flag == flag
+}
+
+// SetFlag sets or clears flag, keeping the other bits as they are.
+func (m *Metadata) SetFlag(flag Flag, on bool) {
+	flags := m.Flags()
+	if on {
+		flags |= flag
+	} else {
+		flags &^= flag
+	}
+	m.SetByte(IndexFlags, byte(flags))
+}
+
+// Encode writes the entries in index order, followed by the end marker.
+func (m *Metadata) Encode(buf *raknet.Buffer) error {
+	for _, index := range m.Indices() {
+		entry := m.entries[index]
+		err := buf.WriteMetadataHeader(entry.Type, index)
+		if err != nil {
+			return fmt.Errorf("metadata %d: %w", index, err)
+		}
+		err = writeValue(buf, entry)
+		if err != nil {
+			return fmt.Errorf("metadata %d: %w", index, err)
+		}
+	}
+	return buf.WriteMetadataEnd()
+}
+
+func writeValue(buf *raknet.Buffer, entry Entry) error {
+	switch v := entry.Value.(type) {
+	case byte:
+		return buf.WriteByte(v)
+	case int16:
+		return buf.WriteShort(v)
+	case int32:
+		return buf.WriteInt(v)
+	case float32:
+		return buf.WriteFloat(v)
+	case string:
+		return buf.WriteString(v)
+	case *item.ItemStack:
+		return buf.WriteItemStack(v)
+	case Vector:
+		err := buf.WriteInt(v.X)
+		if err != nil {
+			return err
+		}
+		err = buf.WriteInt(v.Y)
+		if err != nil {
+			return err
+		}
+		return buf.WriteInt(v.Z)
+	case Rotation:
+		err := buf.WriteFloat(v.X)
+		if err != nil {
+			return err
+		}
+		err = buf.WriteFloat(v.Y)
+		if err != nil {
+			return err
+		}
+		return buf.WriteFloat(v.Z)
+	}
+	return fmt.Errorf("unsupported value %T", entry.Value)
+}
+
+// Decode reads entries up to the end marker, adding them to m.
+func (m *Metadata) Decode(buf *raknet.Buffer) error {
+	for {
+		typ, index, end, err := buf.ReadMetadataHeader()
+		if err != nil || end {
+			return err
+		}
+		value, err := readValue(buf, typ)
+		if err != nil {
+			return fmt.Errorf("metadata %d: %w", index, err)
+		}
+		m.set(index, typ, value)
+	}
+}
+
+func readValue(buf *raknet.Buffer, typ raknet.MetadataType) (any, error) {
+	switch typ {
+	case raknet.MetadataByte:
+		return buf.ReadByte()
+	case raknet.MetadataShort:
+		return buf.ReadShort()
+	case raknet.MetadataInt:
+		return buf.ReadInt()
+	case raknet.MetadataFloat:
+		return buf.ReadFloat()
+	case raknet.MetadataString:
+		return buf.ReadString()
+	case raknet.MetadataSlot:
+		return buf.ReadItemStack()
+	case raknet.MetadataVector:
+		var v Vector
+		var err error
+		for _, c := range []*int32{&v.X, &v.Y, &v.Z} {
+			*c, err = buf.ReadInt()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case raknet.MetadataRotation:
+		var r Rotation
+		var err error
+		for _, c := range []*float32{&r.X, &r.Y, &r.Z} {
+			*c, err = buf.ReadFloat()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown metadata type %d", typ)
+}
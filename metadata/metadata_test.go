@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/item"
+	"github.com/jnaraujo/mcprotocol/raknet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlags(t *testing.T) {
+	m := New()
+	assert.False(t, m.HasFlag(FlagOnFire))
+
+	m.SetFlag(FlagOnFire, true)
+	m.SetFlag(FlagCrouched, true)
+	m.SetFlag(FlagSprinting, true)
+	m.SetFlag(FlagSprinting, false)
+	assert.True(t, m.HasFlag(FlagOnFire|FlagCrouched))
+	assert.False(t, m.HasFlag(FlagSprinting))
+	assert.False(t, m.HasFlag(FlagInvisible))
+	assert.Equal(t, FlagOnFire|FlagCrouched, m.Flags())
+}
+
+func TestEncode(t *testing.T) {
+	m := New()
+	m.SetFloat(IndexHealth, 20)
+	m.SetFlag(FlagOnFire, true)
+	m.SetFlag(FlagCrouched, true)
+
+	buf := raknet.NewBuffer()
+	assert.Nil(t, m.Encode(buf))
+	assert.Equal(t, []byte{
+		0x00, 0x03,
+		0x66, 0x41, 0xa0, 0x00, 0x00,
+		0x7f,
+	}, buf.Bytes())
+}
+
+func TestRoundTrip(t *testing.T) {
+	m := New()
+	m.SetByte(IndexFlags, byte(FlagInvisible))
+	m.SetShort(IndexAir, 300)
+	m.SetInt(IndexPotionColor, 0x1F1F1F)
+	m.SetFloat(IndexHealth, 10.5)
+	m.SetString(IndexNameTag, "Dinnerbone")
+	m.SetSlot(12, item.New(276, 1))
+	m.SetSlot(13, nil)
+	m.SetVector(14, Vector{X: 1, Y: 64, Z: -1})
+	m.SetRotation(15, Rotation{X: 0, Y: 90, Z: 180})
+
+	buf := raknet.NewBuffer()
+	assert.Nil(t, m.Encode(buf))
+
+	decoded := New()
+	assert.Nil(t, decoded.Decode(buf))
+	assert.Equal(t, m, decoded)
+	assert.Equal(t, 0, buf.Len())
+
+	name, ok := decoded.String(IndexNameTag)
+	assert.True(t, ok)
+	assert.Equal(t, "Dinnerbone", name)
+	stack, ok := decoded.Slot(13)
+	assert.True(t, ok)
+	assert.Nil(t, stack)
+	_, ok = decoded.Int(IndexHealth)
+	assert.False(t, ok)
+}
+
+func TestEncodeErrors(t *testing.T) {
+	m := New()
+	m.SetByte(32, 1)
+	assert.ErrorIs(t, m.Encode(raknet.NewBuffer()), raknet.ErrMetadataIndex)
+
+	// a float at 31 would be the end marker
+	m = New()
+	m.SetFloat(31, 1)
+	assert.ErrorIs(t, m.Encode(raknet.NewBuffer()), raknet.ErrMetadataIndex)
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	m := New()
+	err := m.Decode(raknet.NewBufferFrom([]byte{0x66, 0x41, 0xa0}))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// no end marker
+	err = m.Decode(raknet.NewBufferFrom([]byte{0x00, 0x03}))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,87 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// MarshalGzip encodes v like Marshal and gzips it, the way level.dat, player
+// files and item NBT on the wire are stored.
+func MarshalGzip(v any) ([]byte, error) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	err := NewEncoder(zw).Encode("", v)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// MarshalZlib encodes v like Marshal and compresses it with zlib, the way
+// chunks are stored in region files.
+func MarshalZlib(v any) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	err := NewEncoder(zw).Encode("", v)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// MaxNetworkSize is the limit vanilla puts on NBT sent by clients, 2 MiB.
+const MaxNetworkSize = 2 << 20
+
+// UnmarshalCompressed decodes a document that is gzipped, zlib compressed or
+// not compressed at all, telling them apart by their first bytes.
+func UnmarshalCompressed(data []byte, v any) error {
+	return UnmarshalCompressedLimit(data, v, 0)
+}
+
+// UnmarshalCompressedLimit is UnmarshalCompressed failing with ErrTooLarge
+// once the document counts for more than limit bytes, see
+// Decoder.SetLimit. Untrusted documents should be decoded with it, since a
+// few kilobytes can decompress to gigabytes.
+func UnmarshalCompressedLimit(data []byte, v any, limit int64) error {
+	r, err := Decompress(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	d := NewDecoder(r)
+	d.SetLimit(limit)
+	_, err = d.Decode(v)
+	return err
+}
+
+// Decompress wraps r in a gzip or zlib reader if the stream starts with
+// their header, and returns it as is otherwise.
+func Decompress(r io.Reader) (io.Reader, error) {
+	var head [2]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && n == 0 {
+		return nil, err
+	}
+	r = io.MultiReader(bytes.NewReader(head[:n]), r)
+	if n < 2 {
+		return r, nil
+	}
+
+	switch {
+	case head[0] == 0x1f && head[1] == 0x8b:
+		return gzip.NewReader(r)
+	// zlib streams start with the deflate method and a header checksum
+	case head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0:
+		return zlib.NewReader(r)
+	}
+	return r, nil
+}
@@ -0,0 +1,510 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Unmarshal decodes the document in data into v, which must be a pointer.
+func Unmarshal(data []byte, v any) error {
+	_, err := NewDecoder(bytes.NewReader(data)).Decode(v)
+	return err
+}
+
+// tagOverhead is what every decoded tag counts for against the limit of a
+// decoder on top of its payload, standing for the memory it takes once
+// decoded. Without it, a list of millions of empty lists would compress to
+// almost nothing and still fit in the limit.
+const tagOverhead = 16
+
+type Decoder struct {
+	r       io.Reader
+	scratch [8]byte
+	// limit is how many bytes a document may count for, 0 for no limit
+	limit int64
+	size  int64
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetLimit makes Decode fail with ErrTooLarge once a document counts for
+// more than n bytes: every byte read from the input, plus a fixed overhead
+// for every tag. Like the size tracker vanilla uses on NBT sent by clients,
+// it bounds both how much a compressed document can expand and how much
+// memory decoding it takes. A limit of 0 removes it.
+func (d *Decoder) SetLimit(n int64) {
+	d.limit = n
+}
+
+// Decode reads the next document into v, which must be a pointer, and returns
+// the name of its root compound. It returns io.EOF if there is no document
+// left to read.
+func (d *Decoder) Decode(v any) (string, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Pointer || val.IsNil() {
+		return "", fmt.Errorf("%w: Decode needs a non-nil pointer", ErrUnsupportedType)
+	}
+	d.size = 0
+
+	_, err := io.ReadFull(d.r, d.scratch[:1])
+	if err != nil {
+		return "", err
+	}
+	err = d.charge(1)
+	if err != nil {
+		return "", err
+	}
+	if TagType(d.scratch[0]) != TagCompound {
+		return "", ErrNotCompound
+	}
+	name, err := d.readString()
+	if err != nil {
+		return "", err
+	}
+	return name, d.readValue(TagCompound, val.Elem(), 0)
+}
+
+func (d *Decoder) readValue(typ TagType, v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return ErrTooDeep
+	}
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		dyn, err := d.readDynamic(typ, depth)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(dyn))
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.readValue(typ, v.Elem(), depth)
+	}
+
+	err := d.charge(tagOverhead)
+	if err != nil {
+		return err
+	}
+
+	switch typ {
+	case TagByte, TagShort, TagInt, TagLong:
+		n, err := d.readInt(typ)
+		if err != nil {
+			return err
+		}
+		return setInt(v, typ, n)
+	case TagFloat, TagDouble:
+		f, err := d.readFloat(typ)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return &TypeError{Tag: typ, Type: v.Type().String()}
+		}
+		v.SetFloat(f)
+		return nil
+	case TagString:
+		s, err := d.readString()
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.String {
+			return &TypeError{Tag: typ, Type: v.Type().String()}
+		}
+		v.SetString(s)
+		return nil
+	case TagByteArray:
+		if v.Kind() != reflect.Slice || (v.Type().Elem().Kind() != reflect.Uint8 && v.Type().Elem().Kind() != reflect.Int8) {
+			return &TypeError{Tag: typ, Type: v.Type().String()}
+		}
+		b, err := d.readByteArray()
+		if err != nil {
+			return err
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(b)
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), len(b), len(b))
+		for i, c := range b {
+			s.Index(i).SetInt(int64(int8(c)))
+		}
+		v.Set(s)
+		return nil
+	case TagIntArray:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Int32 {
+			return &TypeError{Tag: typ, Type: v.Type().String()}
+		}
+		ints, err := d.readIntArray()
+		if err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(ints), len(ints))
+		for i, n := range ints {
+			s.Index(i).SetInt(int64(n))
+		}
+		v.Set(s)
+		return nil
+	case TagList:
+		if v.Kind() != reflect.Slice {
+			return &TypeError{Tag: typ, Type: v.Type().String()}
+		}
+		return d.readList(v, depth)
+	case TagCompound:
+		switch {
+		case v.Kind() == reflect.Struct:
+			return d.readStruct(v, depth)
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			return d.readMap(v, depth)
+		}
+		return &TypeError{Tag: typ, Type: v.Type().String()}
+	}
+	return fmt.Errorf("%w: %d", ErrUnknownTag, typ)
+}
+
+// setInt stores an integer tag in any integer or bool field it fits in, since
+// vanilla is not consistent about which integer tag it uses for a value.
+func setInt(v reflect.Value, typ TagType, n int64) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(n != 0)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !v.OverflowInt(n) {
+			v.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n >= 0 && !v.OverflowUint(uint64(n)) {
+			v.SetUint(uint64(n))
+			return nil
+		}
+		// the encoder writes unsigned fields in the tag of the same width,
+		// so their upper half comes back negative
+		if bits := v.Type().Bits(); bits == tagBits(typ) {
+			v.SetUint(uint64(n) & (1<<bits - 1))
+			return nil
+		}
+	}
+	return &TypeError{Tag: typ, Type: v.Type().String()}
+}
+
+// tagBits returns the width of an integer tag.
+func tagBits(typ TagType) int {
+	switch typ {
+	case TagByte:
+		return 8
+	case TagShort:
+		return 16
+	case TagInt:
+		return 32
+	}
+	return 64
+}
+
+func (d *Decoder) readList(v reflect.Value, depth int) error {
+	elemType, length, err := d.readListHeader()
+	if err != nil {
+		return err
+	}
+
+	s := reflect.MakeSlice(v.Type(), 0, min(length, 1024))
+	for i := 0; i < length; i++ {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		err = d.readValue(elemType, elem, depth+1)
+		if err != nil {
+			return err
+		}
+		s = reflect.Append(s, elem)
+	}
+	v.Set(s)
+	return nil
+}
+
+func (d *Decoder) readStruct(v reflect.Value, depth int) error {
+	fields := structFields(v.Type())
+	for {
+		typ, name, err := d.readTagHeader()
+		if err != nil || typ == TagEnd {
+			return err
+		}
+
+		found := false
+		for _, f := range fields {
+			if f.name == name {
+				err = d.readValue(typ, v.Field(f.index), depth+1)
+				found = true
+				break
+			}
+		}
+		if !found {
+			_, err = d.readDynamic(typ, depth+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (d *Decoder) readMap(v reflect.Value, depth int) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for {
+		typ, name, err := d.readTagHeader()
+		if err != nil || typ == TagEnd {
+			return err
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		err = d.readValue(typ, elem, depth+1)
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), elem)
+	}
+}
+
+// readDynamic reads a tag into the Go type it maps to by default.
+func (d *Decoder) readDynamic(typ TagType, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, ErrTooDeep
+	}
+	err := d.charge(tagOverhead)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case TagByte:
+		n, err := d.readInt(typ)
+		return int8(n), err
+	case TagShort:
+		n, err := d.readInt(typ)
+		return int16(n), err
+	case TagInt:
+		n, err := d.readInt(typ)
+		return int32(n), err
+	case TagLong:
+		return d.readInt(typ)
+	case TagFloat:
+		f, err := d.readFloat(typ)
+		return float32(f), err
+	case TagDouble:
+		return d.readFloat(typ)
+	case TagString:
+		return d.readString()
+	case TagByteArray:
+		return d.readByteArray()
+	case TagIntArray:
+		return d.readIntArray()
+	case TagList:
+		elemType, length, err := d.readListHeader()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]any, 0, min(length, 1024))
+		for i := 0; i < length; i++ {
+			elem, err := d.readDynamic(elemType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, elem)
+		}
+		return list, nil
+	case TagCompound:
+		c := Compound{}
+		for {
+			typ, name, err := d.readTagHeader()
+			if err != nil {
+				return nil, err
+			}
+			if typ == TagEnd {
+				return c, nil
+			}
+			c[name], err = d.readDynamic(typ, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownTag, typ)
+}
+
+// readTagHeader reads the type and name of the next entry in a compound.
+// TagEnd has no name.
+func (d *Decoder) readTagHeader() (TagType, string, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return TagEnd, "", err
+	}
+	typ := TagType(b[0])
+	if typ == TagEnd {
+		return TagEnd, "", nil
+	}
+	name, err := d.readString()
+	return typ, name, err
+}
+
+func (d *Decoder) readListHeader() (TagType, int, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return TagEnd, 0, err
+	}
+	typ := TagType(b[0])
+	length, err := d.readLength()
+	if err != nil {
+		return TagEnd, 0, err
+	}
+	if typ == TagEnd && length > 0 {
+		return TagEnd, 0, fmt.Errorf("%w: list of TAG_End", ErrUnknownTag)
+	}
+	return typ, length, nil
+}
+
+func (d *Decoder) readInt(typ TagType) (int64, error) {
+	switch typ {
+	case TagByte:
+		b, err := d.read(1)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int8(b[0])), nil
+	case TagShort:
+		b, err := d.read(2)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int16(binary.BigEndian.Uint16(b))), nil
+	case TagInt:
+		b, err := d.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(b))), nil
+	}
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (d *Decoder) readFloat(typ TagType) (float64, error) {
+	if typ == TagFloat {
+		b, err := d.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	}
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (d *Decoder) readString() (string, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return "", err
+	}
+	length := binary.BigEndian.Uint16(b)
+	err = d.charge(int64(length))
+	if err != nil {
+		return "", err
+	}
+	str := make([]byte, length)
+	_, err = io.ReadFull(d.r, str)
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return decodeMUTF8(str), nil
+}
+
+func (d *Decoder) readByteArray() ([]byte, error) {
+	length, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	err = d.charge(int64(length))
+	if err != nil {
+		return nil, err
+	}
+	// copied instead of allocated up front, so a bogus length runs out of
+	// input before it runs out of memory
+	var b bytes.Buffer
+	_, err = io.CopyN(&b, d.r, int64(length))
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return b.Bytes(), nil
+}
+
+func (d *Decoder) readIntArray() ([]int32, error) {
+	length, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	ints := make([]int32, 0, min(length, 1024))
+	for i := 0; i < length; i++ {
+		n, err := d.readInt(TagInt)
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, int32(n))
+	}
+	return ints, nil
+}
+
+func (d *Decoder) readLength() (int, error) {
+	n, err := d.readInt(TagInt)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, ErrNegativeLength
+	}
+	return int(n), nil
+}
+
+// read reads n bytes into the scratch buffer, which the next read reuses.
+// Only the root tag may end at io.EOF, running out anywhere else means the
+// document was cut short.
+func (d *Decoder) read(n int) ([]byte, error) {
+	err := d.charge(int64(n))
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.ReadFull(d.r, d.scratch[:n])
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return d.scratch[:n], nil
+}
+
+// charge counts n more bytes against the limit of the decoder.
+func (d *Decoder) charge(n int64) error {
+	d.size += n
+	if d.limit > 0 && d.size > d.limit {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
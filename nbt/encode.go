@@ -0,0 +1,266 @@
+package nbt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Marshal encodes v as a document with an empty root name, the way item and
+// network NBT is written.
+func Marshal(v any) ([]byte, error) {
+	return MarshalNamed("", v)
+}
+
+// MarshalNamed encodes v as a document whose root compound is called name.
+func MarshalNamed(name string, v any) ([]byte, error) {
+	var b bytes.Buffer
+	err := NewEncoder(&b).Encode(name, v)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+type Encoder struct {
+	w *bufio.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes v as a document whose root compound is called name. v must
+// be a struct, a map with string keys or a Compound.
+func (e *Encoder) Encode(name string, v any) error {
+	val := reflect.ValueOf(v)
+	typ, err := tagTypeOf(val)
+	if err != nil {
+		return err
+	}
+	if typ != TagCompound {
+		return ErrNotCompound
+	}
+
+	e.w.WriteByte(byte(TagCompound))
+	err = e.writeString(name)
+	if err != nil {
+		return err
+	}
+	err = e.writeValue(val, TagCompound, 0)
+	if err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// tagTypeOf picks the tag v is written as, looking inside interfaces and
+// pointers.
+func tagTypeOf(v reflect.Value) (TagType, error) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return TagEnd, fmt.Errorf("%w: nil", ErrUnsupportedType)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return TagByte, nil
+	case reflect.Int16, reflect.Uint16:
+		return TagShort, nil
+	case reflect.Int32, reflect.Uint32:
+		return TagInt, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return TagLong, nil
+	case reflect.Float32:
+		return TagFloat, nil
+	case reflect.Float64:
+		return TagDouble, nil
+	case reflect.String:
+		return TagString, nil
+	case reflect.Slice, reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Uint8, reflect.Int8:
+			return TagByteArray, nil
+		case reflect.Int32:
+			return TagIntArray, nil
+		}
+		return TagList, nil
+	case reflect.Struct:
+		return TagCompound, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return TagEnd, fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+		}
+		return TagCompound, nil
+	}
+	return TagEnd, fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func (e *Encoder) writeValue(v reflect.Value, typ TagType, depth int) error {
+	if depth > maxDepth {
+		return ErrTooDeep
+	}
+	v = indirect(v)
+
+	switch typ {
+	case TagByte:
+		if v.Kind() == reflect.Bool {
+			if v.Bool() {
+				return e.w.WriteByte(1)
+			}
+			return e.w.WriteByte(0)
+		}
+		return e.w.WriteByte(byte(intOf(v)))
+	case TagShort:
+		return e.writeUint(uint64(intOf(v)), 2)
+	case TagInt:
+		return e.writeUint(uint64(intOf(v)), 4)
+	case TagLong:
+		return e.writeUint(uint64(intOf(v)), 8)
+	case TagFloat:
+		return e.writeUint(uint64(math.Float32bits(float32(v.Float()))), 4)
+	case TagDouble:
+		return e.writeUint(math.Float64bits(v.Float()), 8)
+	case TagString:
+		return e.writeString(v.String())
+	case TagByteArray:
+		err := e.writeUint(uint64(v.Len()), 4)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			e.w.WriteByte(byte(intOf(v.Index(i))))
+		}
+		return nil
+	case TagIntArray:
+		err := e.writeUint(uint64(v.Len()), 4)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			e.writeUint(uint64(v.Index(i).Int()), 4)
+		}
+		return nil
+	case TagList:
+		return e.writeList(v, depth)
+	case TagCompound:
+		if v.Kind() == reflect.Struct {
+			return e.writeStruct(v, depth)
+		}
+		return e.writeMap(v, depth)
+	}
+	return fmt.Errorf("%w: %d", ErrUnknownTag, typ)
+}
+
+func intOf(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+func (e *Encoder) writeList(v reflect.Value, depth int) error {
+	// an empty list has no element type to go by
+	elemType := TagEnd
+	for i := 0; i < v.Len(); i++ {
+		typ, err := tagTypeOf(v.Index(i))
+		if err != nil {
+			return err
+		}
+		if i > 0 && typ != elemType {
+			return ErrMixedList
+		}
+		elemType = typ
+	}
+
+	e.w.WriteByte(byte(elemType))
+	err := e.writeUint(uint64(v.Len()), 4)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		err = e.writeValue(v.Index(i), elemType, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) writeMap(v reflect.Value, depth int) error {
+	// sorted so the same map always encodes to the same bytes
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	for _, key := range keys {
+		elem := v.MapIndex(key)
+		if !indirect(elem).IsValid() {
+			continue
+		}
+		err := e.writeNamed(key.String(), elem, depth)
+		if err != nil {
+			return err
+		}
+	}
+	return e.w.WriteByte(byte(TagEnd))
+}
+
+func (e *Encoder) writeStruct(v reflect.Value, depth int) error {
+	for _, f := range structFields(v.Type()) {
+		elem := v.Field(f.index)
+		if !indirect(elem).IsValid() || (f.omitEmpty && elem.IsZero()) {
+			continue
+		}
+		err := e.writeNamed(f.name, elem, depth)
+		if err != nil {
+			return err
+		}
+	}
+	return e.w.WriteByte(byte(TagEnd))
+}
+
+func (e *Encoder) writeNamed(name string, v reflect.Value, depth int) error {
+	typ, err := tagTypeOf(v)
+	if err != nil {
+		return err
+	}
+	e.w.WriteByte(byte(typ))
+	err = e.writeString(name)
+	if err != nil {
+		return err
+	}
+	return e.writeValue(v, typ, depth+1)
+}
+
+func (e *Encoder) writeString(s string) error {
+	b := encodeMUTF8(s)
+	if len(b) > math.MaxUint16 {
+		return fmt.Errorf("nbt: string of %d bytes is too long", len(b))
+	}
+	e.writeUint(uint64(len(b)), 2)
+	_, err := e.w.Write(b)
+	return err
+}
+
+func (e *Encoder) writeUint(v uint64, size int) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, err := e.w.Write(b[8-size:])
+	return err
+}
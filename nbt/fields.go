@@ -0,0 +1,46 @@
+package nbt
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+// structFields lists the exported fields of t under their tag names.
+func structFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("nbt")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     i,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
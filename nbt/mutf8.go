@@ -0,0 +1,68 @@
+package nbt
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// NBT strings are Java's modified UTF-8: NUL is written as two bytes and
+// characters outside the BMP as two 3 byte surrogates. Everything else is
+// the same as UTF-8.
+
+func encodeMUTF8(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == 0:
+			out = append(out, 0xC0, 0x80)
+		case r > 0xFFFF:
+			r1, r2 := utf16.EncodeRune(r)
+			out = appendSurrogate(out, r1)
+			out = appendSurrogate(out, r2)
+		default:
+			out = utf8.AppendRune(out, r)
+		}
+	}
+	return out
+}
+
+// appendSurrogate writes a surrogate half the way UTF-8 would write any
+// other 3 byte character, which utf8.AppendRune refuses to do.
+func appendSurrogate(out []byte, r rune) []byte {
+	return append(out,
+		0xE0|byte(r>>12),
+		0x80|byte(r>>6)&0x3F,
+		0x80|byte(r)&0x3F,
+	)
+}
+
+func decodeMUTF8(b []byte) string {
+	runes := make([]rune, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			runes = append(runes, rune(c))
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(b):
+			runes = append(runes, rune(c&0x1F)<<6|rune(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0 && i+2 < len(b):
+			runes = append(runes, rune(c&0x0F)<<12|rune(b[i+1]&0x3F)<<6|rune(b[i+2]&0x3F))
+			i += 3
+		default:
+			runes = append(runes, utf8.RuneError)
+			i++
+		}
+	}
+	// utf16.Decode joins the surrogate pairs back together
+	units := make([]uint16, 0, len(runes))
+	for _, r := range runes {
+		if r > 0xFFFF {
+			units = append(units, uint16(utf8.RuneError))
+			continue
+		}
+		units = append(units, uint16(r))
+	}
+	return string(utf16.Decode(units))
+}
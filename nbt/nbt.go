@@ -0,0 +1,105 @@
+// Package nbt reads and writes Named Binary Tag, the format items, block
+// entities, player data and world files are stored in.
+//
+// A document is a single named compound tag. Go values map to tags like
+// this:
+//
+//	int8, uint8, bool    Byte
+//	int16, uint16        Short
+//	int32, uint32        Int
+//	int64, int, uint64, uint Long
+//	float32              Float
+//	float64              Double
+//	[]byte               ByteArray
+//	string               String
+//	[]int32              IntArray
+//	other slices         List
+//	struct, map, Compound Compound
+//
+// Struct fields are stored under their name unless tagged `nbt:"Name"`.
+// Fields tagged `nbt:"-"` are skipped and `nbt:",omitempty"` leaves out
+// zero values. When decoding into an interface, tags become the types
+// above, with lists as []any and compounds as Compound. Integer tags can be
+// decoded into any integer field they fit in; unsigned fields also take
+// negative values of their own width, which is how values with the top bit
+// set are encoded.
+package nbt
+
+import (
+	"errors"
+	"fmt"
+)
+
+type TagType byte
+
+const (
+	TagEnd TagType = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+)
+
+func (t TagType) String() string {
+	switch t {
+	case TagEnd:
+		return "TAG_End"
+	case TagByte:
+		return "TAG_Byte"
+	case TagShort:
+		return "TAG_Short"
+	case TagInt:
+		return "TAG_Int"
+	case TagLong:
+		return "TAG_Long"
+	case TagFloat:
+		return "TAG_Float"
+	case TagDouble:
+		return "TAG_Double"
+	case TagByteArray:
+		return "TAG_Byte_Array"
+	case TagString:
+		return "TAG_String"
+	case TagList:
+		return "TAG_List"
+	case TagCompound:
+		return "TAG_Compound"
+	case TagIntArray:
+		return "TAG_Int_Array"
+	}
+	return fmt.Sprintf("TagType(%d)", byte(t))
+}
+
+// Compound is a compound tag whose entries are not known in advance.
+type Compound map[string]any
+
+// maxDepth is how deep lists and compounds may nest, the same limit vanilla
+// uses.
+const maxDepth = 512
+
+var (
+	ErrNotCompound     = errors.New("nbt: root tag is not a compound")
+	ErrUnknownTag      = errors.New("nbt: unknown tag type")
+	ErrTooDeep         = errors.New("nbt: tags nested too deep")
+	ErrNegativeLength  = errors.New("nbt: negative length")
+	ErrMixedList       = errors.New("nbt: list elements have different types")
+	ErrUnsupportedType = errors.New("nbt: unsupported Go type")
+	ErrTooLarge        = errors.New("nbt: document larger than the decoder limit")
+)
+
+// TypeError reports a tag that cannot be stored in a Go value.
+type TypeError struct {
+	Tag  TagType
+	Type string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("nbt: cannot decode %s into %s", e.Tag, e.Type)
+}
@@ -0,0 +1,292 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// helloWorld is hello_world.nbt from the original NBT specification.
+var helloWorld = []byte{
+	0x0a, 0x00, 0x0b, 'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd',
+	0x08, 0x00, 0x04, 'n', 'a', 'm', 'e',
+	0x00, 0x09, 'B', 'a', 'n', 'a', 'n', 'r', 'a', 'm', 'a',
+	0x00,
+}
+
+// enchantedSword is the tag of a sword renamed to Excalibur with Sharpness V,
+// as a 1.7.10 client sends it.
+var enchantedSword = []byte{
+	0x0a, 0x00, 0x00,
+	0x09, 0x00, 0x04, 'e', 'n', 'c', 'h', 0x0a, 0x00, 0x00, 0x00, 0x01,
+	0x02, 0x00, 0x02, 'i', 'd', 0x00, 0x10,
+	0x02, 0x00, 0x03, 'l', 'v', 'l', 0x00, 0x05,
+	0x00,
+	0x0a, 0x00, 0x07, 'd', 'i', 's', 'p', 'l', 'a', 'y',
+	0x08, 0x00, 0x04, 'N', 'a', 'm', 'e', 0x00, 0x09, 'E', 'x', 'c', 'a', 'l', 'i', 'b', 'u', 'r',
+	0x00,
+	0x00,
+}
+
+type enchantment struct {
+	ID    int16 `nbt:"id"`
+	Level int16 `nbt:"lvl"`
+}
+
+type display struct {
+	Name string
+	Lore []string `nbt:",omitempty"`
+}
+
+type swordTag struct {
+	Enchantments []enchantment `nbt:"ench"`
+	Display      display       `nbt:"display"`
+	Ignored      string        `nbt:"-"`
+}
+
+func TestHelloWorld(t *testing.T) {
+	var v struct {
+		Name string `nbt:"name"`
+	}
+	name, err := NewDecoder(bytes.NewReader(helloWorld)).Decode(&v)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", name)
+	assert.Equal(t, "Bananrama", v.Name)
+
+	data, err := MarshalNamed("hello world", v)
+	assert.Nil(t, err)
+	assert.Equal(t, helloWorld, data)
+}
+
+func TestHelloWorldDynamic(t *testing.T) {
+	var c Compound
+	err := Unmarshal(helloWorld, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, Compound{"name": "Bananrama"}, c)
+
+	data, err := MarshalNamed("hello world", c)
+	assert.Nil(t, err)
+	assert.Equal(t, helloWorld, data)
+}
+
+func TestStructTags(t *testing.T) {
+	var tag swordTag
+	err := Unmarshal(enchantedSword, &tag)
+	assert.Nil(t, err)
+	assert.Equal(t, swordTag{
+		Enchantments: []enchantment{{ID: 16, Level: 5}},
+		Display:      display{Name: "Excalibur"},
+	}, tag)
+
+	tag.Ignored = "not written"
+	data, err := Marshal(tag)
+	assert.Nil(t, err)
+	assert.Equal(t, enchantedSword, data)
+}
+
+func TestAllTagTypes(t *testing.T) {
+	in := Compound{
+		"byte":      int8(-2),
+		"short":     int16(-300),
+		"int":       int32(70000),
+		"long":      int64(math.MinInt64),
+		"float":     float32(0.5),
+		"double":    math.Pi,
+		"bytes":     []byte{1, 2, 3},
+		"string":    "café \U0001F600 \x00",
+		"list":      []any{int32(1), int32(2)},
+		"empty":     []any{},
+		"compound":  Compound{"nested": Compound{"deeper": int8(1)}},
+		"ints":      []int32{-1, 0, 1},
+		"compounds": []any{Compound{"a": int8(1)}, Compound{"b": int8(2)}},
+	}
+	data, err := Marshal(in)
+	assert.Nil(t, err)
+
+	var out Compound
+	err = Unmarshal(data, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, in, out)
+
+	var dyn any
+	err = Unmarshal(data, &dyn)
+	assert.Nil(t, err)
+	assert.Equal(t, in, dyn)
+}
+
+func TestModifiedUTF8(t *testing.T) {
+	data, err := Marshal(Compound{"s": "\x00\U0001F600"})
+	assert.Nil(t, err)
+	// NUL is two bytes and the emoji a pair of 3 byte surrogates
+	assert.Equal(t, []byte{0x00, 0x08, 0xC0, 0x80, 0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}, data[7:17])
+}
+
+func TestIntegerConversions(t *testing.T) {
+	// a Byte read into wider fields and a bool, like vanilla's Unbreakable
+	data, err := Marshal(Compound{"a": int8(1), "b": int8(-1), "c": int32(1000)})
+	assert.Nil(t, err)
+
+	var v struct {
+		A bool  `nbt:"a"`
+		B int64 `nbt:"b"`
+		C *int  `nbt:"c"`
+	}
+	err = Unmarshal(data, &v)
+	assert.Nil(t, err)
+	assert.True(t, v.A)
+	assert.Equal(t, int64(-1), v.B)
+	assert.Equal(t, 1000, *v.C)
+
+	var small struct {
+		C int8 `nbt:"c"`
+	}
+	err = Unmarshal(data, &small)
+	var typeErr *TypeError
+	assert.ErrorAs(t, err, &typeErr)
+
+	// a negative byte doesn't fit in a wider unsigned field
+	var unsigned struct {
+		B uint16 `nbt:"b"`
+	}
+	err = Unmarshal(data, &unsigned)
+	assert.ErrorAs(t, err, &typeErr)
+}
+
+func TestUnsignedRoundTrip(t *testing.T) {
+	type unsigned struct {
+		U8  uint8  `nbt:"u8"`
+		U16 uint16 `nbt:"u16"`
+		U32 uint32 `nbt:"u32"`
+		U64 uint64 `nbt:"u64"`
+		U   uint   `nbt:"u"`
+	}
+	for _, expected := range []unsigned{
+		{U8: 1, U16: 2, U32: 3, U64: 4, U: 5},
+		{U8: math.MaxUint8, U16: math.MaxUint16, U32: math.MaxUint32, U64: math.MaxUint64, U: math.MaxUint},
+		{U8: 1 << 7, U16: 1 << 15, U32: 1 << 31, U64: 1 << 63, U: 1 << 63},
+	} {
+		data, err := Marshal(expected)
+		assert.Nil(t, err)
+		var actual unsigned
+		err = Unmarshal(data, &actual)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := Marshal(int32(1))
+	assert.ErrorIs(t, err, ErrNotCompound)
+
+	_, err = Marshal(Compound{"list": []any{int8(1), "two"}})
+	assert.ErrorIs(t, err, ErrMixedList)
+
+	_, err = Marshal(Compound{"c": make(chan int)})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var c Compound
+	err := Unmarshal(helloWorld[:len(helloWorld)-4], &c)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	err = Unmarshal([]byte{0x08, 0x00, 0x00}, &c)
+	assert.ErrorIs(t, err, ErrNotCompound)
+
+	// a byte array claiming 2GiB
+	err = Unmarshal([]byte{0x0a, 0x00, 0x00, 0x07, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff}, &c)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	err = Unmarshal([]byte{0x0a, 0x00, 0x00, 0x0b, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, &c)
+	assert.ErrorIs(t, err, ErrNegativeLength)
+
+	err = Unmarshal([]byte{0x0a, 0x00, 0x00, 0x0d, 0x00, 0x00}, &c)
+	assert.ErrorIs(t, err, ErrUnknownTag)
+
+	deep := []byte{0x0a, 0x00, 0x00}
+	for i := 0; i <= maxDepth; i++ {
+		deep = append(deep, 0x0a, 0x00, 0x00)
+	}
+	err = Unmarshal(deep, &c)
+	assert.ErrorIs(t, err, ErrTooDeep)
+}
+
+func TestCompressed(t *testing.T) {
+	gz, err := MarshalGzip(swordTag{Display: display{Name: "Excalibur"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b}, gz[:2])
+
+	zl, err := MarshalZlib(swordTag{Display: display{Name: "Excalibur"}})
+	assert.Nil(t, err)
+	assert.Equal(t, byte(0x78), zl[0])
+
+	for _, data := range [][]byte{gz, zl, enchantedSword} {
+		var tag swordTag
+		err = UnmarshalCompressed(data, &tag)
+		assert.Nil(t, err)
+		assert.Equal(t, "Excalibur", tag.Display.Name)
+	}
+
+	// a gzipped file written by something else
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(helloWorld)
+	zw.Close()
+	var c Compound
+	err = UnmarshalCompressed(b.Bytes(), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bananrama", c["name"])
+}
+
+// listBomb returns a gzipped compound holding a list of n empty lists,
+// which compresses to almost nothing.
+func listBomb(n int) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte{0x0a, 0x00, 0x00, 0x09, 0x00, 0x01, 'l', 0x09, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	empty := bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, 4096)
+	for i := 0; i < n; i += 4096 {
+		zw.Write(empty[:5*min(4096, n-i)])
+	}
+	zw.Write([]byte{0x00})
+	zw.Close()
+	return b.Bytes()
+}
+
+func TestDecodeLimit(t *testing.T) {
+	bomb := listBomb(1 << 20)
+	assert.Less(t, len(bomb), 16<<10)
+
+	var c Compound
+	err := UnmarshalCompressedLimit(bomb, &c, MaxNetworkSize)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	var s struct {
+		L [][]int8 `nbt:"l"`
+	}
+	err = UnmarshalCompressedLimit(bomb, &s, MaxNetworkSize)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	// the limit counts the payload of arrays before reading them
+	data, err := MarshalGzip(map[string][]byte{"b": make([]byte, 1024)})
+	assert.Nil(t, err)
+	err = UnmarshalCompressedLimit(data, &c, 1000)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	// documents under the limit decode as usual, and the limit is per document
+	small := listBomb(1000)
+	d := NewDecoder(io.MultiReader(bytes.NewReader(helloWorld), bytes.NewReader(helloWorld)))
+	d.SetLimit(int64(len(helloWorld)) + 2*tagOverhead)
+	for range 2 {
+		c = nil
+		_, err = d.Decode(&c)
+		assert.Nil(t, err)
+		assert.Equal(t, "Bananrama", c["name"])
+	}
+	err = UnmarshalCompressedLimit(small, &s, MaxNetworkSize)
+	assert.Nil(t, err)
+	assert.Len(t, s.L, 1000)
+}
@@ -4,14 +4,20 @@ import (
 	"github.com/jnaraujo/mcprotocol/raknet"
 )
 
+// maxVarIntLen is the longest VarInt a packet length can take, since
+// packets are at most MaxPacketSizeInBytes long.
+const maxVarIntLen = 3
+
 type Packet struct {
 	id     PacketID
 	buffer *raknet.Buffer
 }
 
+// NewPacket returns an empty packet whose buffer comes from a pool. Release
+// gives it back once the packet has been sent.
 func NewPacket(id PacketID) *Packet {
 	return &Packet{
-		buffer: raknet.NewBuffer(),
+		buffer: raknet.GetBuffer(),
 		id:     id,
 	}
 }
@@ -27,30 +33,64 @@ func (p *Packet) ID() PacketID {
 	return p.id
 }
 
-func (p *Packet) MarshalBinary() ([]byte, error) {
-	dataBuf := raknet.NewBuffer()
-	err := dataBuf.WriteByte(byte(p.id))
-	if err != nil {
-		return []byte{}, err
+// Release returns the buffer of the packet to the pool. The packet and
+// anything returned by Bytes must not be used afterwards.
+func (p *Packet) Release() {
+	if p.buffer != nil {
+		raknet.PutBuffer(p.buffer)
+		p.buffer = nil
 	}
+}
 
-	_, err = dataBuf.WriteBytes(p.Bytes())
-	if err != nil {
-		return []byte{}, err
+// MarshalBinary frames the packet: its length as a VarInt, its id and its
+// payload.
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	return p.AppendBinary(make([]byte, 0, maxVarIntLen+1+p.buffer.Len()))
+}
+
+// AppendBinary appends the framed packet to dst. The length is not known
+// before the payload has been copied, so room is reserved for the longest
+// VarInt and the length is written right before the id, leaving the unused
+// reserved bytes out of the result.
+func (p *Packet) AppendBinary(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, make([]byte, maxVarIntLen)...)
+	dst = append(dst, byte(p.id))
+	dst = append(dst, p.Bytes()...)
+
+	length := len(dst) - start - maxVarIntLen
+	if length > int(MaxPacketSizeInBytes) {
+		return nil, ErrPacketTooLarge
 	}
 
-	packetBuf := raknet.NewBuffer()
-	err = packetBuf.WriteVarInt(int32(dataBuf.Len()))
-	if err != nil {
-		return []byte{}, err
+	n := varIntLen(length)
+	offset := start + maxVarIntLen - n
+	for i := range n {
+		b := byte(length & 0x7F)
+		length >>= 7
+		if i < n-1 {
+			b |= 0x80
+		}
+		dst[offset+i] = b
 	}
 
-	_, err = packetBuf.WriteBytes(dataBuf.Bytes())
-	if err != nil {
-		return []byte{}, err
+	if start == 0 {
+		// nothing came before, the frame can start past the unused bytes
+		return dst[offset:], nil
 	}
+	// close the gap between what came before and the frame
+	copy(dst[start:], dst[offset:])
+	return dst[:len(dst)-(maxVarIntLen-n)], nil
+}
 
-	return packetBuf.Bytes(), nil
+func varIntLen(n int) int {
+	switch {
+	case n < 1<<7:
+		return 1
+	case n < 1<<14:
+		return 2
+	}
+	return 3
 }
 
 func (p *Packet) UnmarshalBinary(data []byte) error {
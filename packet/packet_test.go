@@ -41,3 +41,39 @@ func TestPacketUnmarshalBinary(t *testing.T) {
 	assert.Equal(t, PacketID(12), p2.ID())
 	assert.Equal(t, []byte{4, 176}, p2.Bytes())
 }
+
+func TestPacketAppendBinary(t *testing.T) {
+	// payloads whose length takes one, two and three bytes
+	for _, size := range []int{0, 200, 20000} {
+		p := NewPacket(7)
+		p.Buffer().WriteBytes(make([]byte, size))
+
+		expected := raknet.NewBuffer()
+		expected.WriteVarInt(int32(size + 1))
+		expected.WriteByte(7)
+		expected.WriteBytes(make([]byte, size))
+
+		b, err := p.MarshalBinary()
+		assert.Nil(t, err)
+		assert.Equal(t, expected.Bytes(), b)
+
+		b, err = p.AppendBinary([]byte("prefix"))
+		assert.Nil(t, err)
+		assert.Equal(t, append([]byte("prefix"), expected.Bytes()...), b)
+		p.Release()
+	}
+}
+
+func BenchmarkMarshalBinary(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		p := NewPacket(IDClientPlayerPosition)
+		p.Buffer().WriteDouble(1.5)
+		p.Buffer().WriteDouble(64)
+		p.Buffer().WriteDouble(65.62)
+		p.Buffer().WriteDouble(-3)
+		p.Buffer().WriteBool(true)
+		p.MarshalBinary()
+		p.Release()
+	}
+}
@@ -0,0 +1,104 @@
+package packet
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+var (
+	ErrPacketTooLarge      = errors.New("packet too large")
+	ErrInvalidPacketLength = errors.New("invalid packet length")
+)
+
+// Reader splits a byte stream into packets. A single read from the
+// connection may hold part of a packet or several of them, so the length
+// prefix is used to find where each packet ends.
+type Reader struct {
+	src *source
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		src: &source{
+			r: bufio.NewReader(r),
+		},
+	}
+}
+
+// EnableEncryption decrypts everything read after the current packet with
+// stream. It must be called from the goroutine reading packets.
+func (r *Reader) EnableEncryption(stream cipher.Stream) {
+	r.src.stream = stream
+}
+
+// PeekByte returns the next byte of the stream without consuming it. It is
+// used before the first packet to tell legacy pings apart from handshakes.
+func (r *Reader) PeekByte() (byte, error) {
+	b, err := r.src.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadPacket blocks until one complete packet has been read. The packet
+// buffer comes from a pool, and can be given back with Release once the
+// packet has been decoded.
+func (r *Reader) ReadPacket() (*Packet, error) {
+	length, err := raknet.ReadVarInt(r.src)
+	if err != nil {
+		return nil, err
+	}
+	if length > MaxPacketSizeInBytes {
+		return nil, ErrPacketTooLarge
+	}
+	// every packet has at least its id
+	if length < 1 {
+		return nil, ErrInvalidPacketLength
+	}
+
+	buf := raknet.GetBuffer()
+	err = buf.ReadFull(r.src, int(length))
+	if err != nil {
+		raknet.PutBuffer(buf)
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	id, _ := buf.ReadByte()
+	return NewPacketFromBuffer(buf, PacketID(id)), nil
+}
+
+// source hands out the bytes read from the connection, decrypting them once
+// encryption has been enabled.
+type source struct {
+	r      *bufio.Reader
+	stream cipher.Stream
+}
+
+func (s *source) ReadByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if s.stream != nil {
+		buf := [1]byte{b}
+		s.stream.XORKeyStream(buf[:], buf[:])
+		b = buf[0]
+	}
+	return b, nil
+}
+
+func (s *source) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if s.stream != nil {
+		s.stream.XORKeyStream(p[:n], p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,105 @@
+package packet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jnaraujo/mcprotocol/raknet"
+	"github.com/stretchr/testify/assert"
+)
+
+func marshalPackets(t *testing.T, pkts ...*Packet) []byte {
+	var out []byte
+	for _, pkt := range pkts {
+		b, err := pkt.MarshalBinary()
+		assert.Nil(t, err)
+		out = append(out, b...)
+	}
+	return out
+}
+
+func TestReaderBackToBack(t *testing.T) {
+	position := NewPacket(IDClientPlayerPosition)
+	position.Buffer().WriteDouble(1.5)
+	position.Buffer().WriteDouble(64)
+	keepAlive := NewPacket(IDClientKeepAlive)
+	keepAlive.Buffer().WriteInt(42)
+
+	r := NewReader(bytes.NewReader(marshalPackets(t, position, keepAlive)))
+
+	pkt, err := r.ReadPacket()
+	assert.Nil(t, err)
+	assert.Equal(t, IDClientPlayerPosition, pkt.ID())
+	x, err := pkt.Buffer().ReadDouble()
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, x)
+
+	pkt, err = r.ReadPacket()
+	assert.Nil(t, err)
+	assert.Equal(t, IDClientKeepAlive, pkt.ID())
+	id, err := pkt.Buffer().ReadInt()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(42), id)
+
+	_, err = r.ReadPacket()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderSplitReads(t *testing.T) {
+	pkt := NewPacket(IDClientChatMessage)
+	pkt.Buffer().WriteString("split across many reads")
+
+	r := NewReader(iotest.OneByteReader(bytes.NewReader(marshalPackets(t, pkt, pkt))))
+
+	for range 2 {
+		actual, err := r.ReadPacket()
+		assert.Nil(t, err)
+		assert.Equal(t, IDClientChatMessage, actual.ID())
+		msg, err := actual.Buffer().ReadString()
+		assert.Nil(t, err)
+		assert.Equal(t, "split across many reads", msg)
+	}
+}
+
+func TestReaderTruncated(t *testing.T) {
+	pkt := NewPacket(IDClientKeepAlive)
+	pkt.Buffer().WriteInt(7)
+	b := marshalPackets(t, pkt)
+
+	r := NewReader(bytes.NewReader(b[:len(b)-1]))
+	_, err := r.ReadPacket()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestReaderTooLarge(t *testing.T) {
+	buf := raknet.NewBuffer()
+	buf.WriteVarInt(MaxPacketSizeInBytes + 1)
+
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	_, err := r.ReadPacket()
+	assert.Equal(t, ErrPacketTooLarge, err)
+}
+
+func TestReaderEmptyPacket(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x00}))
+	_, err := r.ReadPacket()
+	assert.Equal(t, ErrInvalidPacketLength, err)
+}
+
+func TestReaderPeekByte(t *testing.T) {
+	keepAlive := NewPacket(IDClientKeepAlive)
+	keepAlive.Buffer().WriteInt(42)
+	data := marshalPackets(t, keepAlive)
+
+	r := NewReader(bytes.NewReader(data))
+	b, err := r.PeekByte()
+	assert.Nil(t, err)
+	assert.Equal(t, data[0], b)
+
+	// peeking does not consume the packet
+	pkt, err := r.ReadPacket()
+	assert.Nil(t, err)
+	assert.Equal(t, IDClientKeepAlive, pkt.ID())
+}
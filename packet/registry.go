@@ -0,0 +1,152 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+type Direction uint8
+
+const (
+	// Serverbound packets are sent by the client to the server.
+	Serverbound Direction = iota
+	// Clientbound packets are sent by the server to the client.
+	Clientbound
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Serverbound:
+		return "serverbound"
+	case Clientbound:
+		return "clientbound"
+	}
+	return fmt.Sprintf("Direction(%d)", byte(d))
+}
+
+var (
+	ErrUnknownPacket = errors.New("unknown packet")
+)
+
+// Codec is a packet with a concrete Go type, which knows how to read its
+// fields from a packet buffer and write them back.
+type Codec interface {
+	Decode(buf *raknet.Buffer) error
+	Encode(buf *raknet.Buffer) error
+}
+
+// VersionedCodec is implemented by packets whose fields differ between
+// protocol versions. The registry prefers it over Codec when decoding and
+// encoding.
+type VersionedCodec interface {
+	Codec
+	DecodeVersion(buf *raknet.Buffer, version int32) error
+	EncodeVersion(buf *raknet.Buffer, version int32) error
+}
+
+type registryKey struct {
+	version   int32
+	state     fsm.FSMState
+	direction Direction
+	id        PacketID
+}
+
+type registryTypeKey struct {
+	version   int32
+	state     fsm.FSMState
+	direction Direction
+	typ       reflect.Type
+}
+
+// Registry maps packet ids to Go types. The same id means a different packet
+// depending on the protocol version, the connection state and who sent it,
+// so all of them are part of the key.
+type Registry struct {
+	factories map[registryKey]func() Codec
+	ids       map[registryTypeKey]PacketID
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[registryKey]func() Codec),
+		ids:       make(map[registryTypeKey]PacketID),
+	}
+}
+
+// Register adds a packet type. newCodec must return a pointer to a new,
+// empty packet. It panics if the id or the type is already registered for
+// the same version, state and direction.
+func (r *Registry) Register(version int32, state fsm.FSMState, direction Direction, id PacketID, newCodec func() Codec) {
+	key := registryKey{version, state, direction, id}
+	if _, exists := r.factories[key]; exists {
+		panic(fmt.Sprintf("packet: id %s registered twice for version %d, state %d, %s", id, version, state, direction))
+	}
+
+	typeKey := registryTypeKey{version, state, direction, reflect.TypeOf(newCodec())}
+	if _, exists := r.ids[typeKey]; exists {
+		panic(fmt.Sprintf("packet: type %s registered twice for version %d, state %d, %s", typeKey.typ, version, state, direction))
+	}
+
+	r.factories[key] = newCodec
+	r.ids[typeKey] = id
+}
+
+// New returns an empty packet of the Go type registered for id.
+func (r *Registry) New(version int32, state fsm.FSMState, direction Direction, id PacketID) (Codec, bool) {
+	newCodec, exists := r.factories[registryKey{version, state, direction, id}]
+	if !exists {
+		return nil, false
+	}
+	return newCodec(), true
+}
+
+// Lookup decodes pkt into the Go type registered for its id.
+func (r *Registry) Lookup(version int32, state fsm.FSMState, direction Direction, pkt *Packet) (Codec, error) {
+	codec, exists := r.New(version, state, direction, pkt.ID())
+	if !exists {
+		return nil, fmt.Errorf("%w: id %s, version %d, state %d, %s", ErrUnknownPacket, pkt.ID(), version, state, direction)
+	}
+
+	var err error
+	if versioned, ok := codec.(VersionedCodec); ok {
+		err = versioned.DecodeVersion(pkt.Buffer(), version)
+	} else {
+		err = codec.Decode(pkt.Buffer())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codec, nil
+}
+
+// ID returns the id codec's type is registered under.
+func (r *Registry) ID(version int32, state fsm.FSMState, direction Direction, codec Codec) (PacketID, bool) {
+	id, exists := r.ids[registryTypeKey{version, state, direction, reflect.TypeOf(codec)}]
+	return id, exists
+}
+
+// Packet encodes codec into a packet with the id its type is registered
+// under.
+func (r *Registry) Packet(version int32, state fsm.FSMState, direction Direction, codec Codec) (*Packet, error) {
+	id, exists := r.ID(version, state, direction, codec)
+	if !exists {
+		return nil, fmt.Errorf("%w: type %T, version %d, state %d, %s", ErrUnknownPacket, codec, version, state, direction)
+	}
+
+	pkt := NewPacket(id)
+	var err error
+	if versioned, ok := codec.(VersionedCodec); ok {
+		err = versioned.EncodeVersion(pkt.Buffer(), version)
+	} else {
+		err = codec.Encode(pkt.Buffer())
+	}
+	if err != nil {
+		pkt.Release()
+		return nil, err
+	}
+	return pkt, nil
+}
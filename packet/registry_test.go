@@ -0,0 +1,147 @@
+package packet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/raknet"
+	"github.com/stretchr/testify/assert"
+)
+
+type testKeepAlive struct {
+	ID int32
+}
+
+func (p *testKeepAlive) Decode(buf *raknet.Buffer) error { return buf.ReadStruct(p) }
+func (p *testKeepAlive) Encode(buf *raknet.Buffer) error { return buf.WriteStruct(p) }
+
+type testLoginStart struct {
+	Name string
+}
+
+func (p *testLoginStart) Decode(buf *raknet.Buffer) error { return buf.ReadStruct(p) }
+func (p *testLoginStart) Encode(buf *raknet.Buffer) error { return buf.WriteStruct(p) }
+
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(5, fsm.FSMStateLogin, Serverbound, 0x00, func() Codec { return &testLoginStart{} })
+	r.Register(5, fsm.FSMStatePlay, Serverbound, 0x00, func() Codec { return &testKeepAlive{} })
+	r.Register(5, fsm.FSMStatePlay, Clientbound, 0x00, func() Codec { return &testKeepAlive{} })
+	return r
+}
+
+func TestRegistryLookupByState(t *testing.T) {
+	r := newTestRegistry()
+
+	pkt := NewPacket(0x00)
+	pkt.Buffer().WriteString("Notch")
+	codec, err := r.Lookup(5, fsm.FSMStateLogin, Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, &testLoginStart{Name: "Notch"}, codec)
+
+	pkt = NewPacket(0x00)
+	pkt.Buffer().WriteInt(42)
+	codec, err = r.Lookup(5, fsm.FSMStatePlay, Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, &testKeepAlive{ID: 42}, codec)
+}
+
+func TestRegistryUnknownPacket(t *testing.T) {
+	r := newTestRegistry()
+
+	_, err := r.Lookup(5, fsm.FSMStateLogin, Clientbound, NewPacket(0x00))
+	assert.ErrorIs(t, err, ErrUnknownPacket)
+
+	_, err = r.Lookup(47, fsm.FSMStatePlay, Serverbound, NewPacket(0x00))
+	assert.ErrorIs(t, err, ErrUnknownPacket)
+
+	_, err = r.Packet(5, fsm.FSMStateLogin, Clientbound, &testLoginStart{})
+	assert.ErrorIs(t, err, ErrUnknownPacket)
+}
+
+func TestRegistryPacket(t *testing.T) {
+	r := newTestRegistry()
+
+	pkt, err := r.Packet(5, fsm.FSMStatePlay, Clientbound, &testKeepAlive{ID: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, PacketID(0x00), pkt.ID())
+	assert.Equal(t, []byte{0, 0, 0, 7}, pkt.Bytes())
+}
+
+// testFailing writes part of itself before failing, keeping the buffer it
+// was given.
+type testFailing struct {
+	buf *raknet.Buffer
+}
+
+func (p *testFailing) Decode(buf *raknet.Buffer) error { return nil }
+func (p *testFailing) Encode(buf *raknet.Buffer) error {
+	p.buf = buf
+	buf.WriteInt(7)
+	return errors.New("failed")
+}
+
+func TestRegistryPacketReleasesOnError(t *testing.T) {
+	r := NewRegistry()
+	r.Register(5, fsm.FSMStatePlay, Clientbound, 0x00, func() Codec { return &testFailing{} })
+
+	codec := &testFailing{}
+	pkt, err := r.Packet(5, fsm.FSMStatePlay, Clientbound, codec)
+	assert.NotNil(t, err)
+	assert.Nil(t, pkt)
+	// the buffer went back to the pool, which empties it
+	assert.Zero(t, codec.buf.Len())
+}
+
+func TestRegistryDuplicate(t *testing.T) {
+	r := newTestRegistry()
+	assert.Panics(t, func() {
+		r.Register(5, fsm.FSMStatePlay, Clientbound, 0x00, func() Codec { return &testLoginStart{} })
+	})
+	assert.Panics(t, func() {
+		r.Register(5, fsm.FSMStatePlay, Clientbound, 0x01, func() Codec { return &testKeepAlive{} })
+	})
+}
+
+type testVersionedKeepAlive struct {
+	ID int32 `mc:"int" mc47:"varint"`
+}
+
+func (p *testVersionedKeepAlive) Decode(buf *raknet.Buffer) error { return buf.ReadStruct(p) }
+func (p *testVersionedKeepAlive) Encode(buf *raknet.Buffer) error { return buf.WriteStruct(p) }
+func (p *testVersionedKeepAlive) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
+func (p *testVersionedKeepAlive) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+func TestRegistryVersionedCodec(t *testing.T) {
+	r := NewRegistry()
+	r.Register(5, fsm.FSMStatePlay, Clientbound, 0x00, func() Codec { return &testVersionedKeepAlive{} })
+	r.Register(47, fsm.FSMStatePlay, Clientbound, 0x00, func() Codec { return &testVersionedKeepAlive{} })
+
+	pkt, err := r.Packet(5, fsm.FSMStatePlay, Clientbound, &testVersionedKeepAlive{ID: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 7}, pkt.Bytes())
+
+	pkt, err = r.Packet(47, fsm.FSMStatePlay, Clientbound, &testVersionedKeepAlive{ID: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{7}, pkt.Bytes())
+
+	codec, err := r.Lookup(47, fsm.FSMStatePlay, Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, &testVersionedKeepAlive{ID: 7}, codec)
+}
+
+func TestRegistryNew(t *testing.T) {
+	r := newTestRegistry()
+
+	codec, ok := r.New(5, fsm.FSMStateLogin, Serverbound, 0x00)
+	assert.True(t, ok)
+	assert.Equal(t, &testLoginStart{}, codec)
+
+	_, ok = r.New(5, fsm.FSMStateLogin, Clientbound, 0x00)
+	assert.False(t, ok)
+}
@@ -0,0 +1,164 @@
+package packet
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	DefaultWriterQueueSize = 256
+	// maxBatchSizeInBytes caps how much is coalesced into a single write.
+	maxBatchSizeInBytes = 64 * 1024
+)
+
+var (
+	ErrWriterClosed = errors.New("writer closed")
+	ErrQueueFull    = errors.New("outbound queue full")
+)
+
+// frame is either an encoded packet or, when stream is set, a request to
+// encrypt everything queued after it.
+type frame struct {
+	data   []byte
+	stream cipher.Stream
+}
+
+// Writer owns the outbound side of a connection. Packets are queued by any
+// number of goroutines and written by a single one, so frames never
+// interleave on the wire. Packets queued while a write is in progress are
+// sent together in the next write.
+type Writer struct {
+	w      io.Writer
+	queue  chan frame
+	stream cipher.Stream
+
+	mu      sync.RWMutex
+	closed  bool
+	err     error
+	stopped chan struct{}
+}
+
+func NewWriter(w io.Writer, queueSize int) *Writer {
+	writer := &Writer{
+		w:       w,
+		queue:   make(chan frame, queueSize),
+		stopped: make(chan struct{}),
+	}
+	go writer.run()
+	return writer
+}
+
+// WritePacket queues pkt without blocking. It returns ErrQueueFull when the
+// peer is not keeping up with what is being sent to it.
+func (w *Writer) WritePacket(pkt *Packet) error {
+	b, err := pkt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.enqueue(frame{data: b})
+}
+
+// WritePackets queues pkts, in order, as a single entry of the queue, so
+// they take one place in it however many there are.
+func (w *Writer) WritePackets(pkts []*Packet) error {
+	var data []byte
+	for _, pkt := range pkts {
+		b, err := pkt.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		data = append(data, b...)
+	}
+	return w.enqueue(frame{data: data})
+}
+
+// EnableEncryption encrypts every packet queued after this call with stream.
+// Packets queued before it are still sent in the clear.
+func (w *Writer) EnableEncryption(stream cipher.Stream) error {
+	return w.enqueue(frame{stream: stream})
+}
+
+func (w *Writer) enqueue(f frame) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	select {
+	case w.queue <- f:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close flushes the packets already queued and stops the writer. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	<-w.stopped
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.err
+}
+
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	batch := make([]byte, 0, maxBatchSizeInBytes)
+	for f := range w.queue {
+		batch = append(batch[:0], f.data...)
+		stream := f.stream
+
+		// a batch ends where encryption starts, since the bytes before it
+		// go out in the clear
+	coalesce:
+		for stream == nil && len(batch) < maxBatchSizeInBytes {
+			select {
+			case f, ok := <-w.queue:
+				if !ok {
+					break coalesce
+				}
+				batch = append(batch, f.data...)
+				stream = f.stream
+			default:
+				break coalesce
+			}
+		}
+
+		if len(batch) > 0 {
+			err := w.write(batch)
+			if err != nil {
+				w.mu.Lock()
+				w.err = err
+				w.mu.Unlock()
+				return
+			}
+		}
+		if stream != nil {
+			w.stream = stream
+		}
+	}
+}
+
+func (w *Writer) write(batch []byte) error {
+	if w.stream != nil {
+		w.stream.XORKeyStream(batch, batch)
+	}
+	_, err := w.w.Write(batch)
+	return err
+}
@@ -0,0 +1,178 @@
+package packet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedWriter blocks every write until a value is sent on gate.
+type gatedWriter struct {
+	gate chan struct{}
+
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func TestWriterCoalesces(t *testing.T) {
+	gw := &gatedWriter{gate: make(chan struct{})}
+	w := NewWriter(gw, 16)
+
+	for i := range 5 {
+		pkt := NewPacket(IDServerKeepAlive)
+		pkt.Buffer().WriteInt(int32(i))
+		assert.Nil(t, w.WritePacket(pkt))
+	}
+
+	close(gw.gate)
+	assert.Nil(t, w.Close())
+
+	// the first packet may be picked up before the rest are queued
+	assert.LessOrEqual(t, len(gw.writes), 2)
+
+	r := NewReader(bytes.NewReader(bytes.Join(gw.writes, nil)))
+	for i := range 5 {
+		pkt, err := r.ReadPacket()
+		assert.Nil(t, err)
+		id, err := pkt.Buffer().ReadInt()
+		assert.Nil(t, err)
+		assert.Equal(t, int32(i), id)
+	}
+}
+
+func TestWriterQueueFull(t *testing.T) {
+	gw := &gatedWriter{gate: make(chan struct{})}
+	w := NewWriter(gw, 1)
+
+	var err error
+	for range 3 {
+		err = w.WritePacket(NewPacket(IDServerKeepAlive))
+		if err != nil {
+			break
+		}
+	}
+	assert.Equal(t, ErrQueueFull, err)
+
+	close(gw.gate)
+	w.Close()
+}
+
+func TestWriterPacketsTakeOnePlace(t *testing.T) {
+	gw := &gatedWriter{gate: make(chan struct{})}
+	w := NewWriter(gw, 1)
+
+	pkts := make([]*Packet, 3*DefaultWriterQueueSize)
+	for i := range pkts {
+		pkts[i] = NewPacket(IDServerKeepAlive)
+		pkts[i].Buffer().WriteInt(int32(i))
+	}
+	assert.Nil(t, w.WritePackets(pkts))
+
+	close(gw.gate)
+	assert.Nil(t, w.Close())
+
+	r := NewReader(bytes.NewReader(bytes.Join(gw.writes, nil)))
+	for i := range pkts {
+		pkt, err := r.ReadPacket()
+		assert.Nil(t, err)
+		id, err := pkt.Buffer().ReadInt()
+		assert.Nil(t, err)
+		assert.Equal(t, int32(i), id)
+	}
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	out := new(lockedBuffer)
+	w := NewWriter(out, 1024)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				pkt := NewPacket(PacketID(g))
+				pkt.Buffer().WriteString("interleaving would corrupt this")
+				assert.Nil(t, w.WritePacket(pkt))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, w.Close())
+
+	r := NewReader(&out.buf)
+	for range 8 * 50 {
+		pkt, err := r.ReadPacket()
+		assert.Nil(t, err)
+		msg, err := pkt.Buffer().ReadString()
+		assert.Nil(t, err)
+		assert.Equal(t, "interleaving would corrupt this", msg)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriterError(t *testing.T) {
+	w := NewWriter(failingWriter{}, 4)
+	assert.Nil(t, w.WritePacket(NewPacket(IDServerKeepAlive)))
+	assert.EqualError(t, w.Close(), "broken pipe")
+	assert.EqualError(t, w.WritePacket(NewPacket(IDServerKeepAlive)), "broken pipe")
+}
+
+func TestWriterEncryption(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	block, err := aes.NewCipher(secret)
+	assert.Nil(t, err)
+
+	out := new(lockedBuffer)
+	w := NewWriter(out, 16)
+
+	plain := NewPacket(IDServerKeepAlive)
+	plain.Buffer().WriteInt(1)
+	assert.Nil(t, w.WritePacket(plain))
+	assert.Nil(t, w.EnableEncryption(cipher.NewCTR(block, secret)))
+	encrypted := NewPacket(IDServerKeepAlive)
+	encrypted.Buffer().WriteInt(2)
+	assert.Nil(t, w.WritePacket(encrypted))
+	assert.Nil(t, w.Close())
+
+	r := NewReader(&out.buf)
+	pkt, err := r.ReadPacket()
+	assert.Nil(t, err)
+	id, _ := pkt.Buffer().ReadInt()
+	assert.Equal(t, int32(1), id)
+
+	r.EnableEncryption(cipher.NewCTR(block, secret))
+	pkt, err = r.ReadPacket()
+	assert.Nil(t, err)
+	id, _ = pkt.Buffer().ReadInt()
+	assert.Equal(t, int32(2), id)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/client"
+)
+
+type pingOutput struct {
+	Host      string   `json:"host"`
+	Version   string   `json:"version"`
+	Protocol  int      `json:"protocol"`
+	Online    int      `json:"online"`
+	Max       int      `json:"max"`
+	Sample    []string `json:"sample"`
+	MOTD      string   `json:"motd"`
+	LatencyMs float64  `json:"latency_ms"`
+}
+
+// runPing queries a server the same way the multiplayer menu does and
+// returns the exit code.
+func runPing(args []string) int {
+	flags := flag.NewFlagSet("ping", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	timeout := flags.Duration("timeout", 5*time.Second, "give up after this long")
+
+	addrs, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(addrs) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	output, err := ping(ctx, addrs[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ping %s: %v\n", addrs[0], err)
+		return 1
+	}
+
+	if *asJSON {
+		err = json.NewEncoder(os.Stdout).Encode(output)
+	} else {
+		err = printPing(os.Stdout, output)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func ping(ctx context.Context, addr string) (*pingOutput, error) {
+	c, err := client.DialContext(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := make([]string, 0, len(status.Players.Sample))
+	for _, p := range status.Players.Sample {
+		sample = append(sample, p.Name)
+	}
+
+	return &pingOutput{
+		Host:      addr,
+		Version:   status.Version.Name,
+		Protocol:  status.Version.Protocol,
+		Online:    status.Players.Online,
+		Max:       status.Players.Max,
+		Sample:    sample,
+		MOTD:      status.Description.PlainText(),
+		LatencyMs: float64(status.Latency.Microseconds()) / 1000,
+	}, nil
+}
+
+func printPing(w io.Writer, output *pingOutput) error {
+	_, err := fmt.Fprintf(w,
+		"Version:  %s (protocol %d)\nPlayers:  %d/%d\nSample:   %s\nMOTD:     %s\nLatency:  %.1fms\n",
+		output.Version, output.Protocol,
+		output.Online, output.Max,
+		strings.Join(output.Sample, ", "),
+		output.MOTD,
+		output.LatencyMs,
+	)
+	return err
+}
@@ -3,12 +3,19 @@ package player
 import (
 	"errors"
 	"net"
+	"time"
 
 	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/auth"
+	"github.com/jnaraujo/mcprotocol/capture"
 	"github.com/jnaraujo/mcprotocol/fsm"
 	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/world"
 )
 
+// closeFlushTimeout bounds how long Close waits for queued packets to be sent.
+const closeFlushTimeout = 5 * time.Second
+
 type Position struct {
 	X        float64
 	FeetY    float64
@@ -20,29 +27,137 @@ type Position struct {
 type Player struct {
 	UUID uuid.UUID
 	Name string
+	// profile properties (skin, cape) given by the session server
+	Properties []auth.ProfileProperty
 
 	IsLoggedIn bool
 	IsAlive    bool
 	Position   Position
+	// ViewDistance is how many columns around itself the player asked to
+	// see, 0 until it sends its settings
+	ViewDistance int
+	// LoadedChunks are the columns the player was sent and still has
+	LoadedChunks map[world.ChunkPos]bool
+
+	// token sent in the encryption request, checked against the response
+	VerifyToken []byte
 
 	// connection stuff
-	Conn  *net.TCPConn
-	State fsm.FSM
+	// protocol version the client sent in the handshake
+	ProtocolVersion int32
+	Conn            net.Conn
+	State           fsm.FSM
+	reader          *packet.Reader
+	writer          *packet.Writer
+	recorder        *capture.Writer
+}
+
+func NewPlayer(conn net.Conn) *Player {
+	return &Player{
+		Conn:   conn,
+		reader: packet.NewReader(conn),
+		writer: packet.NewWriter(conn, packet.DefaultWriterQueueSize),
+	}
+}
+
+// ReadPacket reads the next packet sent by the player.
+func (p *Player) ReadPacket() (*packet.Packet, error) {
+	if p.reader == nil {
+		return nil, errors.New("conn was not set")
+	}
+	pkt, err := p.reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	if p.recorder != nil {
+		// a broken capture must not break the connection
+		p.recorder.Record(packet.Serverbound, p.State.State(), pkt)
+	}
+	return pkt, nil
 }
 
+// SetRecorder records every packet read from and sent to the player from
+// now on. The caller closes rec once the player is gone.
+func (p *Player) SetRecorder(rec *capture.Writer) {
+	p.recorder = rec
+}
+
+// PeekByte returns the next byte sent by the player without consuming it.
+func (p *Player) PeekByte() (byte, error) {
+	if p.reader == nil {
+		return 0, errors.New("conn was not set")
+	}
+	return p.reader.PeekByte()
+}
+
+// EnableEncryption switches the connection to AES/CFB8 using the shared
+// secret. Packets read or queued after this call are encrypted.
+func (p *Player) EnableEncryption(sharedSecret []byte) error {
+	encrypt, decrypt, err := auth.NewStreams(sharedSecret)
+	if err != nil {
+		return err
+	}
+
+	err = p.writer.EnableEncryption(encrypt)
+	if err != nil {
+		return err
+	}
+	p.reader.EnableEncryption(decrypt)
+	return nil
+}
+
+// SendPacket queues pkt to be sent to the player. It is safe to call from
+// any goroutine.
 func (p *Player) SendPacket(pkt *packet.Packet) error {
-	pktBytes, err := pkt.MarshalBinary()
+	if p.writer == nil {
+		return errors.New("conn was not set")
+	}
+
+	err := p.writer.WritePacket(pkt)
+	if errors.Is(err, packet.ErrQueueFull) {
+		// the client is not reading fast enough, kick it
+		p.Conn.Close()
+	}
 	if err != nil {
-		return nil
+		return err
 	}
 
-	if p.Conn == nil {
+	// only what is actually sent is recorded
+	if p.recorder != nil {
+		p.recorder.Record(packet.Clientbound, p.State.State(), pkt)
+	}
+	return nil
+}
+
+// SendPackets queues pkts together, taking a single place in the queue of
+// the connection. It is meant for bursts of small packets that would
+// otherwise fill it.
+func (p *Player) SendPackets(pkts []*packet.Packet) error {
+	if p.writer == nil {
 		return errors.New("conn was not set")
 	}
 
-	_, err = p.Conn.Write(pktBytes)
+	err := p.writer.WritePackets(pkts)
+	if errors.Is(err, packet.ErrQueueFull) {
+		p.Conn.Close()
+	}
 	if err != nil {
 		return err
 	}
+
+	if p.recorder != nil {
+		for _, pkt := range pkts {
+			p.recorder.Record(packet.Clientbound, p.State.State(), pkt)
+		}
+	}
 	return nil
 }
+
+// Close sends whatever is still queued and closes the connection.
+func (p *Player) Close() error {
+	if p.writer != nil {
+		p.Conn.SetWriteDeadline(time.Now().Add(closeFlushTimeout))
+		p.writer.Close()
+	}
+	return p.Conn.Close()
+}
@@ -0,0 +1,55 @@
+package player
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/capture"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestPlayer returns a player on one end of a local connection.
+func newTestPlayer(t *testing.T) *Player {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+	conn, err := l.Accept()
+	assert.Nil(t, err)
+
+	plr := NewPlayer(conn)
+	t.Cleanup(func() { plr.Close() })
+	return plr
+}
+
+func TestRecordOnlySentPackets(t *testing.T) {
+	plr := newTestPlayer(t)
+	var file bytes.Buffer
+	rec, err := capture.NewWriter(&file)
+	assert.Nil(t, err)
+	plr.SetRecorder(rec)
+
+	assert.Nil(t, plr.SendPacket(packet.NewPacket(packet.IDServerKeepAlive)))
+
+	// nothing can be queued once the writer is closed
+	plr.writer.Close()
+	assert.ErrorIs(t, plr.SendPacket(packet.NewPacket(packet.IDServerChatMessage)), packet.ErrWriterClosed)
+	assert.ErrorIs(t, plr.SendPackets([]*packet.Packet{packet.NewPacket(packet.IDServerChatMessage)}), packet.ErrWriterClosed)
+	assert.Nil(t, rec.Close())
+
+	r, err := capture.NewReader(&file)
+	assert.Nil(t, err)
+	record, err := r.ReadRecord()
+	assert.Nil(t, err)
+	pkt, err := record.Packet()
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerKeepAlive, pkt.ID())
+	_, err = r.ReadRecord()
+	assert.ErrorIs(t, err, io.EOF)
+}
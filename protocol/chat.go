@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+// MaxChatLength is the longest message a client may send, in characters.
+// Longer messages still decode, so the server can disconnect the player
+// with a reason, like vanilla does.
+const MaxChatLength = 100
+
+// ClientChatMessagePacket is a chat message or a command typed by the
+// player.
+type ClientChatMessagePacket struct {
+	Message string `mc:"string"`
+}
+
+func (p *ClientChatMessagePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *ClientChatMessagePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+// Where a ServerChatMessagePacket is shown, since 1.8.
+const (
+	ChatPositionChat   byte = 0
+	ChatPositionSystem byte = 1
+	// ChatPositionActionBar shows the message above the hotbar
+	ChatPositionActionBar byte = 2
+)
+
+// ServerChatMessagePacket shows a message in the chat of the player.
+type ServerChatMessagePacket struct {
+	// JSON is the chat component to show
+	JSON     string `mc:"string,max=32767"`
+	Position byte   `mc:"-" mc47:"byte"`
+}
+
+func (p *ServerChatMessagePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *ServerChatMessagePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+func (p *ServerChatMessagePacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
+
+func (p *ServerChatMessagePacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+// Message decodes the message.
+func (p *ServerChatMessagePacket) Message() (chat.Component, error) {
+	return chat.Parse(p.JSON)
+}
+
+// NewServerChatMessagePacket encodes message to be shown in the chat.
+func NewServerChatMessagePacket(message chat.Component) (*ServerChatMessagePacket, error) {
+	messageJSON, err := message.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return &ServerChatMessagePacket{JSON: messageJSON, Position: ChatPositionChat}, nil
+}
@@ -0,0 +1,239 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/jnaraujo/mcprotocol/raknet"
+	"github.com/jnaraujo/mcprotocol/world"
+)
+
+// ChunkDataPacket sends a column, or some of its sections, to the client.
+// This is the 1.7.10 format: 1.8 changed how sections are laid out.
+type ChunkDataPacket struct {
+	X, Z int32
+	// GroundUpContinuous is set when the whole column is sent, biomes
+	// included. Sent with empty bitmasks, it unloads the column.
+	GroundUpContinuous bool
+	// PrimaryBitMask has a bit set for every section in Data, from the
+	// bottom up
+	PrimaryBitMask uint16
+	// AddBitMask has a bit set for every section in Data that has an add
+	// array
+	AddBitMask uint16
+	// Data is the zlib compressed column, see world.Column.Data
+	Data []byte `mc:"bytes,len=int"`
+}
+
+func (p *ChunkDataPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *ChunkDataPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+// Uncompressed returns Data decompressed.
+func (p *ChunkDataPacket) Uncompressed() ([]byte, error) {
+	return decompressChunk(p.Data)
+}
+
+// NewChunkDataPacket returns a packet sending the whole of col. skyLight
+// must be set in dimensions with a sky, the overworld, and unset in the
+// others.
+func NewChunkDataPacket(col *world.Column, skyLight bool) (*ChunkDataPacket, error) {
+	data, primary, add := col.Data(skyLight, true)
+	compressed, err := compressChunk(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ChunkDataPacket{
+		X:                  col.X,
+		Z:                  col.Z,
+		GroundUpContinuous: true,
+		PrimaryBitMask:     primary,
+		AddBitMask:         add,
+		Data:               compressed,
+	}, nil
+}
+
+// NewChunkUnloadPacket returns a packet making the client forget the column
+// at x, z: an empty column, which only holds its biomes.
+func NewChunkUnloadPacket(x, z int32) (*ChunkDataPacket, error) {
+	return NewChunkDataPacket(world.NewColumn(x, z), false)
+}
+
+func compressChunk(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decompressChunk(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// MaxChunkBulkSize is how much uncompressed column data NewMapChunkBulkPackets
+// puts in a packet, well under the 2 MiB a packet can be even when the data
+// doesn't compress.
+const MaxChunkBulkSize = 1 << 20
+
+// ChunkBulkColumn tells where a column of a Map Chunk Bulk is and which of
+// its sections were sent.
+type ChunkBulkColumn struct {
+	X, Z           int32
+	PrimaryBitMask uint16
+	AddBitMask     uint16
+}
+
+// MapChunkBulkPacket sends many whole columns at once, in a single zlib
+// stream. This is the 1.7.10 format.
+type MapChunkBulkPacket struct {
+	// SkyLight is set when the columns have sky light, in the overworld
+	SkyLight bool
+	Columns  []ChunkBulkColumn
+	// Data is the zlib compressed data of every column, one after the
+	// other, in the order of Columns
+	Data []byte
+}
+
+func (p *MapChunkBulkPacket) Decode(buf *raknet.Buffer) error {
+	count, err := buf.ReadShort()
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return raknet.ErrNegativeLength
+	}
+	length, err := buf.ReadInt()
+	if err != nil {
+		return err
+	}
+	if length < 0 {
+		return raknet.ErrNegativeLength
+	}
+	p.SkyLight, err = buf.ReadBool()
+	if err != nil {
+		return err
+	}
+	p.Data, err = buf.ReadBytes(int(length))
+	if err != nil {
+		return err
+	}
+
+	p.Columns = make([]ChunkBulkColumn, count)
+	for i := range p.Columns {
+		err = buf.ReadStruct(&p.Columns[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *MapChunkBulkPacket) Encode(buf *raknet.Buffer) error {
+	if len(p.Columns) > math.MaxInt16 {
+		return fmt.Errorf("too many columns in a chunk bulk: %d", len(p.Columns))
+	}
+	err := buf.WriteShort(int16(len(p.Columns)))
+	if err != nil {
+		return err
+	}
+	err = buf.WriteInt(int32(len(p.Data)))
+	if err != nil {
+		return err
+	}
+	err = buf.WriteBool(p.SkyLight)
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteBytes(p.Data)
+	if err != nil {
+		return err
+	}
+
+	for _, col := range p.Columns {
+		err = buf.WriteStruct(col)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Uncompressed returns the data of every column, decompressed and split.
+func (p *MapChunkBulkPacket) Uncompressed() ([][]byte, error) {
+	data, err := decompressChunk(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([][]byte, len(p.Columns))
+	for i, col := range p.Columns {
+		size := world.DataSize(col.PrimaryBitMask, col.AddBitMask, p.SkyLight, true)
+		if size > len(data) {
+			return nil, fmt.Errorf("chunk bulk data too short for column %d, %d", col.X, col.Z)
+		}
+		columns[i], data = data[:size], data[size:]
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("%d bytes left after the chunk bulk columns", len(data))
+	}
+	return columns, nil
+}
+
+// NewMapChunkBulkPackets returns packets sending the whole of every column
+// in cols, in order. A packet is started whenever the next column would
+// take the data of the current one over MaxChunkBulkSize.
+func NewMapChunkBulkPackets(cols []*world.Column, skyLight bool) ([]*MapChunkBulkPacket, error) {
+	var pkts []*MapChunkBulkPacket
+	var columns []ChunkBulkColumn
+	var data []byte
+
+	flush := func() error {
+		if len(columns) == 0 {
+			return nil
+		}
+		compressed, err := compressChunk(data)
+		if err != nil {
+			return err
+		}
+		pkts = append(pkts, &MapChunkBulkPacket{SkyLight: skyLight, Columns: columns, Data: compressed})
+		columns, data = nil, nil
+		return nil
+	}
+
+	for _, col := range cols {
+		colData, primary, add := col.Data(skyLight, true)
+		if len(data)+len(colData) > MaxChunkBulkSize || len(columns) == math.MaxInt16 {
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+		}
+		columns = append(columns, ChunkBulkColumn{X: col.X, Z: col.Z, PrimaryBitMask: primary, AddBitMask: add})
+		data = append(data, colData...)
+	}
+
+	err := flush()
+	if err != nil {
+		return nil, err
+	}
+	return pkts, nil
+}
@@ -1,44 +1,30 @@
 package protocol
 
-import "github.com/jnaraujo/mcprotocol/packet"
+import "github.com/jnaraujo/mcprotocol/raknet"
 
 type ClientSettings struct {
-	Locale       string
+	Locale       string `mc:"string,max=16"`
 	ViewDistance byte
 	ChatFlags    byte
 	ChatColours  bool
-	Difficulty   byte
-	ShowCape     bool
+	Difficulty   byte `mc47:"-"`
+	ShowCape     bool `mc47:"-"`
+	// 1.8 replaced the cape flag with a bit set of skin layers
+	SkinParts byte `mc:"-" mc47:"byte"`
 }
 
-func ReceiveClientSettings(pkt *packet.Packet) (*ClientSettings, error) {
-	settings := &ClientSettings{}
+func (p *ClientSettings) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *ClientSettings) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
 
-	var err error
+func (p *ClientSettings) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
 
-	settings.Locale, err = pkt.Buffer().ReadString()
-	if err != nil {
-		return nil, err
-	}
-	settings.ViewDistance, err = pkt.Buffer().ReadByte()
-	if err != nil {
-		return nil, err
-	}
-	settings.ChatFlags, err = pkt.Buffer().ReadByte()
-	if err != nil {
-		return nil, err
-	}
-	settings.ChatColours, err = pkt.Buffer().ReadBool()
-	if err != nil {
-		return nil, err
-	}
-	settings.Difficulty, err = pkt.Buffer().ReadByte()
-	if err != nil {
-		return nil, err
-	}
-	settings.ShowCape, err = pkt.Buffer().ReadBool()
-	if err != nil {
-		return nil, err
-	}
-	return settings, nil
+func (p *ClientSettings) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
 }
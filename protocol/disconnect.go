@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+// DisconnectPacket kicks a player that is already playing. Players that are
+// still logging in are kicked with LoginDisconnectPacket.
+type DisconnectPacket struct {
+	Reason string `mc:"string,max=32767"`
+}
+
+func (p *DisconnectPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *DisconnectPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+// Message decodes the reason.
+func (p *DisconnectPacket) Message() (chat.Component, error) {
+	return chat.Parse(p.Reason)
+}
+
+// NewDisconnectPacket encodes reason as the chat JSON the client shows.
+func NewDisconnectPacket(reason chat.Component) (*DisconnectPacket, error) {
+	reasonJSON, err := reason.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return &DisconnectPacket{Reason: reasonJSON}, nil
+}
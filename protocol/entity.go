@@ -0,0 +1,30 @@
+package protocol
+
+import (
+	"github.com/jnaraujo/mcprotocol/metadata"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+// EntityMetadataPacket updates some of the metadata of an entity. Entries it
+// leaves out keep their value.
+type EntityMetadataPacket struct {
+	EntityID int32
+	Metadata metadata.Metadata
+}
+
+func (p *EntityMetadataPacket) Decode(buf *raknet.Buffer) error {
+	var err error
+	p.EntityID, err = buf.ReadInt()
+	if err != nil {
+		return err
+	}
+	return p.Metadata.Decode(buf)
+}
+
+func (p *EntityMetadataPacket) Encode(buf *raknet.Buffer) error {
+	err := buf.WriteInt(p.EntityID)
+	if err != nil {
+		return err
+	}
+	return p.Metadata.Encode(buf)
+}
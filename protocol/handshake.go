@@ -1,8 +1,6 @@
 package protocol
 
-import (
-	"github.com/jnaraujo/mcprotocol/packet"
-)
+import "github.com/jnaraujo/mcprotocol/raknet"
 
 type NextState int32
 
@@ -12,34 +10,16 @@ const (
 )
 
 type HandshakePacket struct {
-	ProtocolVersion int32
-	NextState       NextState
-	Addr            string
+	ProtocolVersion int32  `mc:"varint"`
+	Addr            string `mc:"string,max=255"`
 	Port            uint16
+	NextState       NextState `mc:"varint"`
 }
 
-func ReceiveHandshakePacket(pkt *packet.Packet) (*HandshakePacket, error) {
-	protocolVersion, err := pkt.Buffer().ReadVarInt()
-	if err != nil {
-		return nil, err
-	}
-	addr, err := pkt.Buffer().ReadString()
-	if err != nil {
-		return nil, err
-	}
-	port, err := pkt.Buffer().ReadUShort()
-	if err != nil {
-		return nil, err
-	}
-	nextState, err := pkt.Buffer().ReadVarInt()
-	if err != nil {
-		return nil, err
-	}
+func (p *HandshakePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	return &HandshakePacket{
-		ProtocolVersion: protocolVersion,
-		Addr:            addr,
-		Port:            port,
-		NextState:       NextState(nextState),
-	}, nil
+func (p *HandshakePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
 }
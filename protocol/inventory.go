@@ -0,0 +1,92 @@
+package protocol
+
+import (
+	"github.com/jnaraujo/mcprotocol/item"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+// PlayerInventoryWindow is the window id of the player's own inventory.
+const PlayerInventoryWindow = 0
+
+// SetSlotPacket changes a single slot of a window. Window -1 and slot -1
+// set the item held by the cursor.
+type SetSlotPacket struct {
+	WindowID int8
+	Slot     int16
+	Item     *item.ItemStack
+}
+
+func (p *SetSlotPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *SetSlotPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+// WindowItemsPacket replaces every slot of a window. Empty slots are nil.
+type WindowItemsPacket struct {
+	WindowID byte
+	Items    []*item.ItemStack
+}
+
+func (p *WindowItemsPacket) Decode(buf *raknet.Buffer) error {
+	var err error
+	p.WindowID, err = buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	count, err := buf.ReadShort()
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return raknet.ErrNegativeLength
+	}
+
+	p.Items = make([]*item.ItemStack, 0, count)
+	for i := int16(0); i < count; i++ {
+		stack, err := buf.ReadItemStack()
+		if err != nil {
+			return err
+		}
+		p.Items = append(p.Items, stack)
+	}
+	return nil
+}
+
+func (p *WindowItemsPacket) Encode(buf *raknet.Buffer) error {
+	if len(p.Items) > 32767 {
+		return raknet.ErrTooBig
+	}
+	err := buf.WriteByte(p.WindowID)
+	if err != nil {
+		return err
+	}
+	err = buf.WriteShort(int16(len(p.Items)))
+	if err != nil {
+		return err
+	}
+	for _, stack := range p.Items {
+		err = buf.WriteItemStack(stack)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreativeInventoryActionPacket is sent by a client in creative mode when it
+// puts an item in a slot, or drops it when Slot is -1.
+type CreativeInventoryActionPacket struct {
+	Slot int16
+	Item *item.ItemStack
+}
+
+func (p *CreativeInventoryActionPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *CreativeInventoryActionPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// LegacyPingID is the first byte sent by clients before 1.7 to ask for the
+// server list information. A modern client starts with the length of its
+// handshake instead, which never begins with this byte in practice.
+const LegacyPingID = 0xFE
+
+// legacyKickID is the id of the kick packet that carries the legacy ping
+// answer.
+const legacyKickID = 0xFF
+
+// legacyPingProtocol is reported to legacy clients instead of our protocol
+// version. It is newer than any of them, so they show the server as
+// incompatible instead of trying to join it.
+const legacyPingProtocol = 127
+
+// NewLegacyPingResponse encodes status the way clients from 1.4 to 1.6
+// expect it: a kick packet whose reason is a "§1" marker followed by the
+// protocol, version name, MOTD, online and max players, separated by NUL
+// characters and encoded as UTF-16BE.
+func NewLegacyPingResponse(status StatusResponse) []byte {
+	reason := strings.Join([]string{
+		"§1",
+		strconv.Itoa(legacyPingProtocol),
+		status.Version.Name,
+		status.Description.Legacy(),
+		strconv.Itoa(status.Players.Online),
+		strconv.Itoa(status.Players.Max),
+	}, "\x00")
+
+	chars := utf16.Encode([]rune(reason))
+
+	out := make([]byte, 0, 3+2*len(chars))
+	out = append(out, legacyKickID)
+	// the length is in UTF-16 code units, not bytes
+	out = binary.BigEndian.AppendUint16(out, uint16(len(chars)))
+	for _, c := range chars {
+		out = binary.BigEndian.AppendUint16(out, c)
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLegacyPingResponse(t *testing.T) {
+	response := NewLegacyPingResponse(StatusResponse{
+		Version:     StatusResponseVersion{Name: "1.7.10", Protocol: 5},
+		Description: StatusResponseDescription{Text: "Hi"},
+		Players:     StatusResponsePlayers{Online: 3, Max: 20},
+	})
+
+	reason := "§1\x00127\x001.7.10\x00Hi\x003\x0020"
+	expected := []byte{0xFF, 0x00, byte(len([]rune(reason)))}
+	for _, r := range reason {
+		expected = append(expected, byte(r>>8), byte(r))
+	}
+	assert.Equal(t, expected, response)
+}
@@ -1,165 +1,151 @@
 package protocol
 
 import (
-	"crypto/rand"
-
-	"github.com/jnaraujo/mcprotocol/api/uuid"
-	"github.com/jnaraujo/mcprotocol/auth"
-	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/raknet"
 )
 
 type LoginStartPacket struct {
-	Name string
+	Name string `mc:"string,max=16"`
 }
 
-func ReceiveLoginStartPacket(pkt *packet.Packet) (*LoginStartPacket, error) {
-	name, err := pkt.Buffer().ReadString()
-	if err != nil {
-		return nil, err
-	}
-	return &LoginStartPacket{
-		Name: name,
-	}, nil
+func (p *LoginStartPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
 }
 
-type EncryptionResponsePacket struct {
-	SharedSecret []byte
-	VerifyToken  []byte
+func (p *LoginStartPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
 }
 
-func ReceiveEncryptionResponsePacket(pkt *packet.Packet) (*EncryptionResponsePacket, error) {
-	sharedSecretLength, err := pkt.Buffer().ReadVarInt()
-	if err != nil {
-		return nil, err
-	}
+type EncryptionRequestPacket struct {
+	ServerID    string `mc:"string,max=20"`
+	PublicKey   []byte `mc:"bytes,len=short" mc47:"bytes,len=varint"`
+	VerifyToken []byte `mc:"bytes,len=short" mc47:"bytes,len=varint"`
+}
 
-	sharedSecret, err := pkt.Buffer().ReadBytes(int(sharedSecretLength))
-	if err != nil {
-		return nil, err
-	}
+func (p *EncryptionRequestPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	verifyTokenLength, err := pkt.Buffer().ReadVarInt()
-	if err != nil {
-		return nil, err
-	}
+func (p *EncryptionRequestPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
 
-	verifyToken, err := pkt.Buffer().ReadBytes(int(verifyTokenLength))
-	if err != nil {
-		return nil, err
-	}
+func (p *EncryptionRequestPacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
 
-	return &EncryptionResponsePacket{
-		SharedSecret: sharedSecret,
-		VerifyToken:  verifyToken,
-	}, nil
+func (p *EncryptionRequestPacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
 }
 
-func CreateEncryptionRequestPacket(crypto *auth.Crypto) (*packet.Packet, error) {
-	pkt := packet.NewPacket(0x01)
+type EncryptionResponsePacket struct {
+	SharedSecret []byte `mc:"bytes,len=short" mc47:"bytes,len=varint"`
+	VerifyToken  []byte `mc:"bytes,len=short" mc47:"bytes,len=varint"`
+}
 
-	rndBytes := make([]byte, 4)
-	rand.Read(rndBytes)
+func (p *EncryptionResponsePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	err := pkt.Buffer().WriteString("")
-	if err != nil {
-		return nil, err
-	}
-	err = pkt.Buffer().WriteVarInt(int32(len(crypto.PublicKeyBytes())))
-	if err != nil {
-		return nil, err
-	}
-	_, err = pkt.Buffer().WriteBytes(crypto.PublicKeyBytes())
-	if err != nil {
-		return nil, err
-	}
-	err = pkt.Buffer().WriteVarInt(4)
-	if err != nil {
-		return nil, err
-	}
-	_, err = pkt.Buffer().WriteBytes(rndBytes)
-	if err != nil {
-		return nil, err
-	}
-	err = pkt.Buffer().WriteBool(false)
-	if err != nil {
-		return nil, err
-	}
+func (p *EncryptionResponsePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
 
-	return pkt, nil
+func (p *EncryptionResponsePacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
 }
 
-func CreateLoginSuccessPacket(playerUUID uuid.UUID, playerUsername string) (*packet.Packet, error) {
-	pkt := packet.NewPacket(0x02)
+func (p *EncryptionResponsePacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
 
-	err := pkt.Buffer().WriteString(playerUUID.String())
-	if err != nil {
-		return nil, err
-	}
+type LoginDisconnectPacket struct {
+	Reason string `mc:"string,max=32767"`
+}
 
-	err = pkt.Buffer().WriteString(playerUsername)
-	if err != nil {
-		return nil, err
-	}
+func (p *LoginDisconnectPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	return pkt, nil
+func (p *LoginDisconnectPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
 }
 
-func CreateJoinGamePacket() (*packet.Packet, error) {
-	pkt := packet.NewPacket(0x01)
+// Message decodes the reason.
+func (p *LoginDisconnectPacket) Message() (chat.Component, error) {
+	return chat.Parse(p.Reason)
+}
 
-	// entity id
-	err := pkt.Buffer().WriteInt(0)
+// NewLoginDisconnectPacket encodes reason as the chat JSON the client shows.
+func NewLoginDisconnectPacket(reason chat.Component) (*LoginDisconnectPacket, error) {
+	reasonJSON, err := reason.JSON()
 	if err != nil {
 		return nil, err
 	}
+	return &LoginDisconnectPacket{Reason: reasonJSON}, nil
+}
+
+type LoginSuccessPacket struct {
+	// hyphenated, as the 1.7.10 client expects
+	UUID     string `mc:"string,max=36"`
+	Username string `mc:"string,max=16"`
+}
 
-	// game mode
+func (p *LoginSuccessPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *LoginSuccessPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+type JoinGamePacket struct {
+	EntityID int32
 	// 0: survival, 1: creative, 2: adventure. Bit 3 (0x8) is the hardcore flag
-	err = pkt.Buffer().WriteByte(1)
-	if err != nil {
-		return nil, err
-	}
-	// Dimension
+	GameMode byte
 	// -1: nether, 0: overworld, 1: end
-	err = pkt.Buffer().WriteByte(0)
-	if err != nil {
-		return nil, err
-	}
-	// Difficulty
+	Dimension int8
 	// 0 thru 3 for Peaceful, Easy, Normal, Hard
-	err = pkt.Buffer().WriteByte(1)
-	if err != nil {
-		return nil, err
-	}
-	// Max Players
-	err = pkt.Buffer().WriteByte(2)
-	if err != nil {
-		return nil, err
-	}
-	// level type
+	Difficulty byte
+	MaxPlayers byte
 	// default, flat, largeBiomes, amplified, default_1_1
-	err = pkt.Buffer().WriteString("default")
-	if err != nil {
-		return nil, err
-	}
+	LevelType        string `mc:"string,max=16"`
+	ReducedDebugInfo bool   `mc:"-" mc47:"bool"`
+}
 
-	return pkt, nil
+func (p *JoinGamePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
 }
 
-func CreateSpawnPositionPacket() (*packet.Packet, error) {
-	pkt := packet.NewPacket(packet.IDServerSpawnPosition)
+func (p *JoinGamePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
 
-	err := pkt.Buffer().WriteInt(0) // x
-	if err != nil {
-		return nil, err
-	}
-	err = pkt.Buffer().WriteInt(200) // y
-	if err != nil {
-		return nil, err
-	}
-	err = pkt.Buffer().WriteInt(0) // z
-	if err != nil {
-		return nil, err
-	}
-	return pkt, nil
+func (p *JoinGamePacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
+
+func (p *JoinGamePacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+type SpawnPositionPacket struct {
+	Location raknet.Position `mc47:"position"`
+}
+
+func (p *SpawnPositionPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *SpawnPositionPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+func (p *SpawnPositionPacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
+
+func (p *SpawnPositionPacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
 }
@@ -1,31 +1,27 @@
 package protocol
 
-import (
-	"github.com/jnaraujo/mcprotocol/packet"
-)
+import "github.com/jnaraujo/mcprotocol/raknet"
 
 type PingRequestPacket struct {
 	Payload int64
 }
 
-func ReceivePingRequestPacket(pkt *packet.Packet) (*PingRequestPacket, error) {
-	payload, err := pkt.Buffer().ReadLong()
-	if err != nil {
-		return nil, err
-	}
+func (p *PingRequestPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	return &PingRequestPacket{
-		Payload: payload,
-	}, nil
+func (p *PingRequestPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
 }
 
-func CreatePingResponsePacket(payload int64) (*packet.Packet, error) {
-	pkt := packet.NewPacket(0x01)
+type PingResponsePacket struct {
+	Payload int64
+}
 
-	err := pkt.Buffer().WriteLong(payload)
-	if err != nil {
-		return nil, err
-	}
+func (p *PingResponsePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	return pkt, nil
+func (p *PingResponsePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
 }
@@ -1,34 +1,190 @@
 package protocol
 
 import (
-	"github.com/jnaraujo/mcprotocol/packet"
 	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/raknet"
 )
 
-func ReceivePlayerPosition(pkt *packet.Packet) (*player.Position, error) {
-	pp := new(player.Position)
+// playerEyeHeight is the distance between a standing player's feet and head.
+const playerEyeHeight = 1.62
 
-	var err error
-	pp.X, err = pkt.Buffer().ReadDouble()
+type KeepAlivePacket struct {
+	ID int32 `mc:"int" mc47:"varint"`
+}
+
+func (p *KeepAlivePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *KeepAlivePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+func (p *KeepAlivePacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
+
+func (p *KeepAlivePacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+// PlayerPacket is used to indicate whether the player is on ground
+// (walking/swimming), or airborne (jumping/falling).
+type PlayerPacket struct {
+	OnGround bool
+}
+
+func (p *PlayerPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *PlayerPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+type PlayerPositionPacket struct {
+	X     float64
+	FeetY float64
+	// 1.8 clients only send the feet position
+	HeadY    float64 `mc47:"-"`
+	Z        float64
+	OnGround bool
+}
+
+func (p *PlayerPositionPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *PlayerPositionPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+func (p *PlayerPositionPacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	err := buf.ReadStructVersion(p, version)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	pp.FeetY, err = pkt.Buffer().ReadDouble()
-	if err != nil {
-		return nil, err
+	if version >= Version1_8 {
+		p.HeadY = p.FeetY + playerEyeHeight
 	}
-	pp.HeadY, err = pkt.Buffer().ReadDouble()
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+func (p *PlayerPositionPacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+// Position returns the position the packet moves the player to.
+func (p *PlayerPositionPacket) Position() player.Position {
+	return player.Position{
+		X:        p.X,
+		FeetY:    p.FeetY,
+		HeadY:    p.HeadY,
+		Z:        p.Z,
+		OnGround: p.OnGround,
 	}
-	pp.Z, err = pkt.Buffer().ReadDouble()
+}
+
+// PlayerPositionAndLookPacket is sent by the client when it moves and turns
+// at the same time.
+type PlayerPositionAndLookPacket struct {
+	X     float64
+	FeetY float64
+	// 1.8 clients only send the feet position
+	HeadY    float64 `mc47:"-"`
+	Z        float64
+	Yaw      float32
+	Pitch    float32
+	OnGround bool
+}
+
+func (p *PlayerPositionAndLookPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *PlayerPositionAndLookPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+func (p *PlayerPositionAndLookPacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	err := buf.ReadStructVersion(p, version)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if version >= Version1_8 {
+		p.HeadY = p.FeetY + playerEyeHeight
+	}
+	return nil
+}
+
+func (p *PlayerPositionAndLookPacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+// Position returns the position the packet moves the player to.
+func (p *PlayerPositionAndLookPacket) Position() player.Position {
+	return player.Position{
+		X:        p.X,
+		FeetY:    p.FeetY,
+		HeadY:    p.HeadY,
+		Z:        p.Z,
+		OnGround: p.OnGround,
 	}
-	pp.OnGround, err = pkt.Buffer().ReadBool()
+}
+
+// ServerPlayerPositionAndLookPacket moves the player. The client answers
+// with its own Player Position And Look, and the first one it receives makes
+// it leave the "Downloading terrain" screen.
+type ServerPlayerPositionAndLookPacket struct {
+	X float64
+	// 1.7.10 clients are sent the position of the eyes, 1.8 ones of the feet
+	HeadY float64 `mc47:"-"`
+	FeetY float64 `mc:"-" mc47:"double"`
+	Z     float64
+	Yaw   float32
+	Pitch float32
+	// OnGround was replaced in 1.8 by Flags
+	OnGround bool `mc47:"-"`
+	// Flags marks which of the fields are relative to the current position
+	// and look
+	Flags byte `mc:"-" mc47:"byte"`
+}
+
+func (p *ServerPlayerPositionAndLookPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *ServerPlayerPositionAndLookPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+func (p *ServerPlayerPositionAndLookPacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	err := buf.ReadStructVersion(p, version)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if version >= Version1_8 {
+		p.HeadY = p.FeetY + playerEyeHeight
+	} else {
+		p.FeetY = p.HeadY - playerEyeHeight
 	}
+	return nil
+}
 
-	return pp, nil
+func (p *ServerPlayerPositionAndLookPacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
+}
+
+// NewServerPlayerPositionAndLookPacket returns a packet moving the player
+// to pos, looking in the given direction.
+func NewServerPlayerPositionAndLookPacket(pos player.Position, yaw, pitch float32) *ServerPlayerPositionAndLookPacket {
+	return &ServerPlayerPositionAndLookPacket{
+		X:        pos.X,
+		HeadY:    pos.FeetY + playerEyeHeight,
+		FeetY:    pos.FeetY,
+		Z:        pos.Z,
+		Yaw:      yaw,
+		Pitch:    pitch,
+		OnGround: pos.OnGround,
+	}
 }
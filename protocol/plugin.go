@@ -1,46 +1,25 @@
 package protocol
 
-import "github.com/jnaraujo/mcprotocol/packet"
+import "github.com/jnaraujo/mcprotocol/raknet"
 
 type PluginMessage struct {
-	Channel string
-	Length  int16
-	Data    []byte
+	Channel string `mc:"string,max=20"`
+	// 1.8 dropped the length, the data is the rest of the packet
+	Data []byte `mc:"bytes,len=short" mc47:"bytes"`
 }
 
-func ReceivePluginMessage(pkt *packet.Packet) (*PluginMessage, error) {
-	pm := &PluginMessage{}
+func (p *PluginMessage) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
 
-	var err error
-	pm.Channel, err = pkt.Buffer().ReadString()
-	if err != nil {
-		return nil, err
-	}
-	pm.Length, err = pkt.Buffer().ReadShort()
-	if err != nil {
-		return nil, err
-	}
-	pm.Data, err = pkt.Buffer().ReadBytes(int(pm.Length))
-	if err != nil {
-		return nil, err
-	}
-	return pm, err
+func (p *PluginMessage) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
 }
 
-func CreatePluginMessagePacket(pm *PluginMessage) (*packet.Packet, error) {
-	pkt := packet.NewPacket(0x3F)
+func (p *PluginMessage) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.ReadStructVersion(p, version)
+}
 
-	err := pkt.Buffer().WriteString(pm.Channel)
-	if err != nil {
-		return nil, err
-	}
-	err = pkt.Buffer().WriteShort(pm.Length)
-	if err != nil {
-		return nil, err
-	}
-	_, err = pkt.Buffer().WriteBytes(pm.Data)
-	if err != nil {
-		return nil, err
-	}
-	return pkt, err
+func (p *PluginMessage) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	return buf.WriteStructVersion(p, version)
 }
@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+)
+
+// Registry holds every packet this package knows how to decode and encode,
+// for every supported version. Packets whose fields changed between versions
+// implement packet.VersionedCodec.
+var Registry = packet.NewRegistry()
+
+func init() {
+	// the packets used so far kept their ids in 1.8
+	for v := range SupportedVersions {
+		register(v)
+	}
+}
+
+func register(v int32) {
+	Registry.Register(v, fsm.FSMStateHandshake, packet.Serverbound, 0x00, func() packet.Codec { return &HandshakePacket{} })
+
+	Registry.Register(v, fsm.FSMStateStatus, packet.Serverbound, 0x00, func() packet.Codec { return &StatusRequestPacket{} })
+	Registry.Register(v, fsm.FSMStateStatus, packet.Serverbound, 0x01, func() packet.Codec { return &PingRequestPacket{} })
+	Registry.Register(v, fsm.FSMStateStatus, packet.Clientbound, 0x00, func() packet.Codec { return &StatusResponsePacket{} })
+	Registry.Register(v, fsm.FSMStateStatus, packet.Clientbound, 0x01, func() packet.Codec { return &PingResponsePacket{} })
+
+	Registry.Register(v, fsm.FSMStateLogin, packet.Serverbound, 0x00, func() packet.Codec { return &LoginStartPacket{} })
+	Registry.Register(v, fsm.FSMStateLogin, packet.Serverbound, 0x01, func() packet.Codec { return &EncryptionResponsePacket{} })
+	Registry.Register(v, fsm.FSMStateLogin, packet.Clientbound, 0x00, func() packet.Codec { return &LoginDisconnectPacket{} })
+	Registry.Register(v, fsm.FSMStateLogin, packet.Clientbound, 0x01, func() packet.Codec { return &EncryptionRequestPacket{} })
+	Registry.Register(v, fsm.FSMStateLogin, packet.Clientbound, 0x02, func() packet.Codec { return &LoginSuccessPacket{} })
+
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientKeepAlive, func() packet.Codec { return &KeepAlivePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientChatMessage, func() packet.Codec { return &ClientChatMessagePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientPlayer, func() packet.Codec { return &PlayerPacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientPlayerPosition, func() packet.Codec { return &PlayerPositionPacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientPlayerPositionAndLook, func() packet.Codec { return &PlayerPositionAndLookPacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientTabComplete, func() packet.Codec { return &ClientTabCompletePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientClientSettings, func() packet.Codec { return &ClientSettings{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientPluginMessage, func() packet.Codec { return &PluginMessage{} })
+
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerKeepAlive, func() packet.Codec { return &KeepAlivePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerJoinGame, func() packet.Codec { return &JoinGamePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerChatMessage, func() packet.Codec { return &ServerChatMessagePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerSpawnPosition, func() packet.Codec { return &SpawnPositionPacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerPlayPositionAndLook, func() packet.Codec { return &ServerPlayerPositionAndLookPacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerRespawn, func() packet.Codec { return &RespawnPacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerTabComplete, func() packet.Codec { return &ServerTabCompletePacket{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerPluginMessage, func() packet.Codec { return &PluginMessage{} })
+	Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerDisconnect, func() packet.Codec { return &DisconnectPacket{} })
+
+	// 1.8 stopped gzipping the NBT of slots and changed the layout of chunk
+	// sections
+	if v == Version1_7_10 {
+		Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerChunkData, func() packet.Codec { return &ChunkDataPacket{} })
+		Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerMapChunkBulk, func() packet.Codec { return &MapChunkBulkPacket{} })
+		Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerEntityMetadata, func() packet.Codec { return &EntityMetadataPacket{} })
+		Registry.Register(v, fsm.FSMStatePlay, packet.Serverbound, packet.IDClientCreativeInventoryAction, func() packet.Codec { return &CreativeInventoryActionPacket{} })
+		Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerSetSlot, func() packet.Codec { return &SetSlotPacket{} })
+		Registry.Register(v, fsm.FSMStatePlay, packet.Clientbound, packet.IDServerWindowItems, func() packet.Codec { return &WindowItemsPacket{} })
+	}
+}
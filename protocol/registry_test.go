@@ -0,0 +1,260 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/item"
+	"github.com/jnaraujo/mcprotocol/nbt"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/raknet"
+	"github.com/jnaraujo/mcprotocol/world"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpawnPositionPerVersion(t *testing.T) {
+	spawn := &SpawnPositionPacket{Location: raknet.Position{X: 1, Y: 64, Z: -1}}
+
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, spawn)
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerSpawnPosition, pkt.ID())
+	assert.Equal(t, 12, pkt.Buffer().Len())
+
+	pkt, err = Registry.Packet(Version1_8, fsm.FSMStatePlay, packet.Clientbound, spawn)
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerSpawnPosition, pkt.ID())
+	assert.Equal(t, 8, pkt.Buffer().Len())
+
+	codec, err := Registry.Lookup(Version1_8, fsm.FSMStatePlay, packet.Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, spawn, codec)
+}
+
+func TestPlayerPosition1_8(t *testing.T) {
+	pkt := packet.NewPacket(packet.IDClientPlayerPosition)
+	pkt.Buffer().WriteDouble(1.5)
+	pkt.Buffer().WriteDouble(64)
+	pkt.Buffer().WriteDouble(-3)
+	pkt.Buffer().WriteBool(true)
+
+	codec, err := Registry.Lookup(Version1_8, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.Nil(t, err)
+
+	position := codec.(*PlayerPositionPacket).Position()
+	assert.Equal(t, 1.5, position.X)
+	assert.Equal(t, 64.0, position.FeetY)
+	assert.Equal(t, 64+playerEyeHeight, position.HeadY)
+	assert.Equal(t, -3.0, position.Z)
+	assert.True(t, position.OnGround)
+}
+
+func TestPlayerPositionAndLookPerVersion(t *testing.T) {
+	pos := &PlayerPositionAndLookPacket{X: 1.5, FeetY: 64, HeadY: 64 + playerEyeHeight, Z: -3, Yaw: 90, Pitch: -45, OnGround: true}
+
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Serverbound, pos)
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDClientPlayerPositionAndLook, pkt.ID())
+	assert.Equal(t, 41, pkt.Buffer().Len())
+	codec, err := Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, pos, codec)
+
+	// 1.8 clients only send the feet position
+	pkt, err = Registry.Packet(Version1_8, fsm.FSMStatePlay, packet.Serverbound, pos)
+	assert.Nil(t, err)
+	assert.Equal(t, 33, pkt.Buffer().Len())
+	codec, err = Registry.Lookup(Version1_8, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, pos, codec)
+	assert.Equal(t, pos.Position(), codec.(*PlayerPositionAndLookPacket).Position())
+}
+
+func TestClientSettings1_8(t *testing.T) {
+	pkt := packet.NewPacket(packet.IDClientClientSettings)
+	pkt.Buffer().WriteString("en_US")
+	pkt.Buffer().WriteByte(8)
+	pkt.Buffer().WriteByte(0)
+	pkt.Buffer().WriteBool(true)
+	pkt.Buffer().WriteByte(0x7F)
+
+	codec, err := Registry.Lookup(Version1_8, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, &ClientSettings{
+		Locale:       "en_US",
+		ViewDistance: 8,
+		ChatColours:  true,
+		SkinParts:    0x7F,
+	}, codec)
+}
+
+func TestKeepAlivePerVersion(t *testing.T) {
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, &KeepAlivePacket{ID: 300})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 0, 1, 44}, pkt.Bytes())
+
+	pkt, err = Registry.Packet(Version1_8, fsm.FSMStatePlay, packet.Clientbound, &KeepAlivePacket{ID: 300})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xac, 0x02}, pkt.Bytes())
+}
+
+func TestWindowItems(t *testing.T) {
+	sword := item.New(276, 1)
+	sword.Enchant(item.Sharpness, 5)
+	items := &WindowItemsPacket{
+		WindowID: PlayerInventoryWindow,
+		Items:    []*item.ItemStack{nil, item.New(1, 64), sword},
+	}
+
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, items)
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerWindowItems, pkt.ID())
+
+	codec, err := Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, items, codec)
+
+	_, err = Registry.Packet(Version1_8, fsm.FSMStatePlay, packet.Clientbound, items)
+	assert.NotNil(t, err)
+}
+
+func TestCreativeInventoryActionTooLarge(t *testing.T) {
+	// a compound holding a list of a million empty lists, a few kilobytes
+	// gzipped and gigabytes once decoded
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte{0x0a, 0x00, 0x00, 0x09, 0x00, 0x01, 'l', 0x09, 0x00, 0x10, 0x00, 0x00})
+	zw.Write(bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, 1<<20))
+	zw.Write([]byte{0x00})
+	zw.Close()
+
+	pkt := packet.NewPacket(packet.IDClientCreativeInventoryAction)
+	pkt.Buffer().WriteShort(36)
+	pkt.Buffer().WriteSlot(&raknet.Slot{ID: 1, Count: 1, NBT: b.Bytes()})
+
+	_, err := Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.ErrorIs(t, err, nbt.ErrTooLarge)
+}
+
+func TestDisconnectReason(t *testing.T) {
+	reason := chat.FromLegacy("§cBanned")
+	disconnect, err := NewDisconnectPacket(reason)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"text":"Banned","color":"red"}`, disconnect.Reason)
+
+	message, err := disconnect.Message()
+	assert.Nil(t, err)
+	assert.Equal(t, reason, message)
+}
+
+func TestTabCompletePerVersion(t *testing.T) {
+	request := &ClientTabCompletePacket{Text: "/tp ", LookedAt: &raknet.Position{X: 1, Y: 64, Z: -1}}
+
+	pkt, err := Registry.Packet(Version1_8, fsm.FSMStatePlay, packet.Serverbound, request)
+	assert.Nil(t, err)
+	codec, err := Registry.Lookup(Version1_8, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, request, codec)
+
+	// 1.7.10 sends the text alone
+	pkt, err = Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Serverbound, request)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, pkt.Buffer().Len())
+	codec, err = Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Serverbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, &ClientTabCompletePacket{Text: "/tp "}, codec)
+}
+
+func TestChunkData(t *testing.T) {
+	col := world.NewColumn(3, -2)
+	col.SetBlock(0, 64, 0, 1)
+	chunk, err := NewChunkDataPacket(col, true)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(1<<4), chunk.PrimaryBitMask)
+
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, chunk)
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerChunkData, pkt.ID())
+	assert.Equal(t, 17+len(chunk.Data), pkt.Buffer().Len())
+	codec, err := Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk, codec)
+
+	data, err := chunk.Uncompressed()
+	assert.Nil(t, err)
+	expected, _, _ := col.Data(true, true)
+	assert.Equal(t, expected, data)
+
+	unload, err := NewChunkUnloadPacket(3, -2)
+	assert.Nil(t, err)
+	assert.True(t, unload.GroundUpContinuous)
+	assert.Zero(t, unload.PrimaryBitMask)
+	assert.Zero(t, unload.AddBitMask)
+	data, err = unload.Uncompressed()
+	assert.Nil(t, err)
+	assert.Len(t, data, 256)
+}
+
+func TestServerPositionAndLookPerVersion(t *testing.T) {
+	pos := NewServerPlayerPositionAndLookPacket(player.Position{X: 0.5, FeetY: 4, Z: -0.5}, 90, 0)
+
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, pos)
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerPlayPositionAndLook, pkt.ID())
+	assert.Equal(t, 33, pkt.Buffer().Len())
+	codec, err := Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, pos, codec)
+
+	pkt, err = Registry.Packet(Version1_8, fsm.FSMStatePlay, packet.Clientbound, pos)
+	assert.Nil(t, err)
+	assert.Equal(t, 33, pkt.Buffer().Len())
+	// 1.8 clients get the feet position
+	codec, err = Registry.Lookup(Version1_8, fsm.FSMStatePlay, packet.Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, pos, codec)
+	assert.Equal(t, 4.0, codec.(*ServerPlayerPositionAndLookPacket).FeetY)
+}
+
+func TestMapChunkBulk(t *testing.T) {
+	full := world.NewColumn(0, 0)
+	for y := 0; y < world.Height; y += world.SectionSize {
+		full.SetBlock(0, y, 0, 0x101)
+	}
+	fullSize := world.DataSize(0xFFFF, 0xFFFF, true, true)
+	perPacket := MaxChunkBulkSize / fullSize
+
+	cols := make([]*world.Column, perPacket+2)
+	for i := range cols {
+		col := *full
+		col.X = int32(i)
+		cols[i] = &col
+	}
+	bulks, err := NewMapChunkBulkPackets(cols, true)
+	assert.Nil(t, err)
+	assert.Len(t, bulks, 2)
+	assert.Len(t, bulks[0].Columns, perPacket)
+	assert.Len(t, bulks[1].Columns, 2)
+	assert.Equal(t, ChunkBulkColumn{X: int32(perPacket + 1), PrimaryBitMask: 0xFFFF, AddBitMask: 0xFFFF}, bulks[1].Columns[1])
+
+	pkt, err := Registry.Packet(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, bulks[1])
+	assert.Nil(t, err)
+	assert.Equal(t, packet.IDServerMapChunkBulk, pkt.ID())
+	assert.Equal(t, 7+len(bulks[1].Data)+2*12, pkt.Buffer().Len())
+	codec, err := Registry.Lookup(Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, pkt)
+	assert.Nil(t, err)
+	assert.Equal(t, bulks[1], codec)
+
+	columns, err := bulks[1].Uncompressed()
+	assert.Nil(t, err)
+	expected, _, _ := full.Data(true, true)
+	assert.Equal(t, [][]byte{expected, expected}, columns)
+
+	// the bitmasks must match the data
+	bulks[1].Columns[0].AddBitMask = 0
+	_, err = bulks[1].Uncompressed()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,25 @@
+package protocol
+
+import "github.com/jnaraujo/mcprotocol/raknet"
+
+// RespawnPacket moves the player to another dimension. The client throws
+// away the world it has loaded, which is also how a player is moved between
+// servers without reconnecting.
+type RespawnPacket struct {
+	// -1: nether, 0: overworld, 1: end
+	Dimension int32
+	// 0 thru 3 for Peaceful, Easy, Normal, Hard
+	Difficulty byte
+	// 0: survival, 1: creative, 2: adventure
+	GameMode byte
+	// default, flat, largeBiomes, amplified, default_1_1
+	LevelType string `mc:"string,max=16"`
+}
+
+func (p *RespawnPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *RespawnPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
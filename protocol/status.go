@@ -3,7 +3,8 @@ package protocol
 import (
 	"encoding/json"
 
-	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/raknet"
 )
 
 type StatusResponseVersion struct {
@@ -18,9 +19,10 @@ type StatusResponsePlayers struct {
 		ID   string `json:"id"`
 	} `json:"sample"`
 }
-type StatusResponseDescription struct {
-	Text string `json:"text"`
-}
+
+// StatusResponseDescription is the MOTD. Some servers send it as a plain
+// string, which chat.Component also accepts.
+type StatusResponseDescription = chat.Component
 
 type StatusResponse struct {
 	Version            StatusResponseVersion     `json:"version,omitempty"`
@@ -30,14 +32,44 @@ type StatusResponse struct {
 	EnforcesSecureChat bool                      `json:"enforcesSecureChat,omitempty"`
 }
 
-func CreateStatusResponsePacket(response StatusResponse) (*packet.Packet, error) {
-	respBytes, err := json.Marshal(response)
+type StatusRequestPacket struct{}
+
+func (p *StatusRequestPacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *StatusRequestPacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+type StatusResponsePacket struct {
+	// StatusResponse encoded as JSON
+	Response string `mc:"string,max=32767"`
+}
+
+func (p *StatusResponsePacket) Decode(buf *raknet.Buffer) error {
+	return buf.ReadStruct(p)
+}
+
+func (p *StatusResponsePacket) Encode(buf *raknet.Buffer) error {
+	return buf.WriteStruct(p)
+}
+
+// Status decodes the status response JSON.
+func (p *StatusResponsePacket) Status() (*StatusResponse, error) {
+	response := &StatusResponse{}
+	err := json.Unmarshal([]byte(p.Response), response)
 	if err != nil {
 		return nil, err
 	}
+	return response, nil
+}
 
-	p := packet.NewPacket(0x00)
-	p.Buffer().WriteString(string(respBytes))
-
-	return p, nil
+// NewStatusResponsePacket encodes response as the status response JSON.
+func NewStatusResponsePacket(response StatusResponse) (*StatusResponsePacket, error) {
+	respBytes, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	return &StatusResponsePacket{Response: string(respBytes)}, nil
 }
@@ -0,0 +1,91 @@
+package protocol
+
+import "github.com/jnaraujo/mcprotocol/raknet"
+
+// ClientTabCompletePacket asks for completions of the chat line being typed
+// when the player presses tab.
+type ClientTabCompletePacket struct {
+	Text string
+	// LookedAt is the block the player is looking at, sent since 1.8
+	LookedAt *raknet.Position
+}
+
+func (p *ClientTabCompletePacket) Decode(buf *raknet.Buffer) error {
+	return p.DecodeVersion(buf, Version1_7_10)
+}
+
+func (p *ClientTabCompletePacket) Encode(buf *raknet.Buffer) error {
+	return p.EncodeVersion(buf, Version1_7_10)
+}
+
+func (p *ClientTabCompletePacket) DecodeVersion(buf *raknet.Buffer, version int32) error {
+	var err error
+	p.Text, err = buf.ReadString()
+	if err != nil || version < Version1_8 {
+		return err
+	}
+
+	hasPosition, err := buf.ReadBool()
+	if err != nil || !hasPosition {
+		return err
+	}
+	position, err := buf.ReadPosition()
+	if err != nil {
+		return err
+	}
+	p.LookedAt = &position
+	return nil
+}
+
+func (p *ClientTabCompletePacket) EncodeVersion(buf *raknet.Buffer, version int32) error {
+	err := buf.WriteString(p.Text)
+	if err != nil || version < Version1_8 {
+		return err
+	}
+
+	err = buf.WriteBool(p.LookedAt != nil)
+	if err != nil || p.LookedAt == nil {
+		return err
+	}
+	return buf.WritePosition(*p.LookedAt)
+}
+
+// ServerTabCompletePacket answers ClientTabCompletePacket with the words
+// that can replace the last word of the line.
+type ServerTabCompletePacket struct {
+	Matches []string
+}
+
+func (p *ServerTabCompletePacket) Decode(buf *raknet.Buffer) error {
+	count, err := buf.ReadVarInt()
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return raknet.ErrNegativeLength
+	}
+
+	p.Matches = make([]string, 0, min(int(count), buf.Len()))
+	for range count {
+		match, err := buf.ReadString()
+		if err != nil {
+			return err
+		}
+		p.Matches = append(p.Matches, match)
+	}
+	return nil
+}
+
+func (p *ServerTabCompletePacket) Encode(buf *raknet.Buffer) error {
+	err := buf.WriteVarInt(int32(len(p.Matches)))
+	if err != nil {
+		return err
+	}
+	for _, match := range p.Matches {
+		err = buf.WriteString(match)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package protocol
+
+const (
+	// Version1_7_10 is the protocol version number of Minecraft 1.7.10.
+	Version1_7_10 int32 = 5
+	// Version1_8 is the protocol version number of Minecraft 1.8.
+	Version1_8 int32 = 47
+)
+
+// SupportedVersions maps the protocol versions this package can speak to
+// their game version names.
+var SupportedVersions = map[int32]string{
+	Version1_7_10: "1.7.10",
+	Version1_8:    "1.8",
+}
+
+func IsSupportedVersion(version int32) bool {
+	_, ok := SupportedVersions[version]
+	return ok
+}
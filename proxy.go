@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jnaraujo/mcprotocol/proxy"
+)
+
+// runProxy starts a proxy in front of the backends in a config file and
+// returns the exit code.
+func runProxy(args []string) int {
+	flags := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	addr := flags.String("addr", ":25565", "address to listen on")
+
+	paths, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(paths) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	config, err := proxy.LoadConfig(paths[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	err = proxy.NewProxy(*addr, config).Listen()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
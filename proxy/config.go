@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+var (
+	ErrNoBackends     = errors.New("no backends configured")
+	ErrUnknownBackend = errors.New("unknown backend")
+)
+
+// Backend is a server players can be sent to. Backends must run in offline
+// mode and, for 1.8, with compression disabled: the proxy does the player
+// facing part of the login and only splices packets afterwards.
+type Backend struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+}
+
+type Config struct {
+	// Backends players can be sent to. Players land on the first one unless
+	// ForcedHosts says otherwise.
+	Backends []Backend `json:"backends"`
+	// ForcedHosts maps the hostname a player connected through to the name
+	// of the backend they land on.
+	ForcedHosts map[string]string `json:"forced_hosts,omitempty"`
+}
+
+// LoadConfig reads a JSON config file.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Backend returns the backend called name.
+func (c Config) Backend(name string) (Backend, error) {
+	for _, backend := range c.Backends {
+		if backend.Name == name {
+			return backend, nil
+		}
+	}
+	return Backend{}, fmt.Errorf("%w: %q", ErrUnknownBackend, name)
+}
+
+// Pick returns the backend a player connecting through host lands on. host
+// is the address sent in the handshake, with or without a port.
+func (c Config) Pick(host string) (Backend, error) {
+	if len(c.Backends) == 0 {
+		return Backend{}, ErrNoBackends
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	// Forge clients append a marker to the hostname
+	host, _, _ = strings.Cut(host, "\x00")
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if name, ok := c.ForcedHosts[host]; ok {
+		return c.Backend(name)
+	}
+	return c.Backends[0], nil
+}
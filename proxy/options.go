@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+type Option func(*Proxy)
+
+// Hooks let the caller look at and change play packets on their way through
+// the proxy. They are called from the goroutines splicing the session, so
+// hooks of the same session may run concurrently.
+type Hooks struct {
+	// JoinGame is called with the Join Game packet of every backend the
+	// session connects to, before the proxy uses it. The client only sees
+	// the first one; after a switch it gets Respawn packets made from it.
+	JoinGame func(s *Session, pkt *protocol.JoinGamePacket)
+	// Respawn is called for every Respawn packet sent to the client,
+	// including the ones made up when switching servers.
+	Respawn func(s *Session, pkt *protocol.RespawnPacket)
+	// Packet is called with every other play packet, in both directions.
+	// It returns the packet to forward, which may be pkt itself, a new
+	// packet, or nil to drop it. Session.RewriteEntityID helps with packets
+	// that refer to the player's own entity.
+	//
+	// The proxy owns both packets: the hook must not release pkt, and must
+	// not keep either of them once it returns. When the hook returns
+	// anything other than pkt, the proxy releases pkt right away, and it
+	// releases the packet it forwards once it has been sent.
+	Packet func(s *Session, direction packet.Direction, pkt *packet.Packet) *packet.Packet
+}
+
+func WithHooks(hooks Hooks) Option {
+	return func(p *Proxy) {
+		p.hooks = hooks
+	}
+}
@@ -0,0 +1,291 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/client"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+// backendTimeout bounds how long connecting and logging in to a backend may
+// take.
+const backendTimeout = 10 * time.Second
+
+// Proxy is the front door for a set of backends. It takes the client
+// through the handshake and login itself, logs in to a backend with the
+// same name and then passes play packets between the two.
+type Proxy struct {
+	addr   string
+	config Config
+	hooks  Hooks
+}
+
+func NewProxy(addr string, config Config, opts ...Option) *Proxy {
+	p := &Proxy{
+		addr:   addr,
+		config: config,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Proxy) Listen() error {
+	addr, err := net.ResolveTCPAddr("tcp", p.addr)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	for {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return err
+		}
+		go p.handleConnection(conn)
+	}
+}
+
+func (p *Proxy) handleConnection(conn *net.TCPConn) {
+	slog.Info("New connection", "addr", conn.RemoteAddr().String())
+
+	plr := player.NewPlayer(conn)
+	defer plr.Close()
+
+	codec, err := p.readPacket(plr)
+	if err != nil {
+		logReadError(err)
+		return
+	}
+	handshake, ok := codec.(*protocol.HandshakePacket)
+	if !ok {
+		slog.Error("Unexpected packet in handshake", "type", fmt.Sprintf("%T", codec))
+		return
+	}
+	plr.ProtocolVersion = handshake.ProtocolVersion
+
+	backend, err := p.config.Pick(handshake.Addr)
+	if err != nil {
+		slog.Error("error picking backend", "host", handshake.Addr, "err", err.Error())
+		return
+	}
+
+	switch handshake.NextState {
+	case protocol.HandshakeNextStateStatus:
+		plr.State.SetState(fsm.FSMStateStatus)
+		p.handleStatus(plr, backend)
+	case protocol.HandshakeNextStateLogin:
+		plr.State.SetState(fsm.FSMStateLogin)
+		p.handleLogin(plr, backend)
+	default:
+		slog.Error("next state not implemented", "state", handshake.NextState)
+	}
+}
+
+// handleStatus answers the server list with the status of the backend the
+// player would land on.
+func (p *Proxy) handleStatus(plr *player.Player, backend Backend) {
+	for {
+		codec, err := p.readPacket(plr)
+		if err != nil {
+			logReadError(err)
+			return
+		}
+
+		switch pkt := codec.(type) {
+		case *protocol.StatusRequestPacket:
+			status, err := p.backendStatus(plr, backend)
+			if err != nil {
+				slog.Error("error querying backend status", "backend", backend.Name, "err", err.Error())
+				return
+			}
+
+			statusRespPkt, err := protocol.NewStatusResponsePacket(*status)
+			if err != nil {
+				slog.Error("Error creating status response packet", "err", err.Error())
+				return
+			}
+			err = p.sendPacket(plr, statusRespPkt)
+			if err != nil {
+				slog.Error("Error sending status response bytes", "err", err.Error())
+				return
+			}
+		case *protocol.PingRequestPacket:
+			err := p.sendPacket(plr, &protocol.PingResponsePacket{Payload: pkt.Payload})
+			if err != nil {
+				slog.Error("error sending ping response packet", "err", err.Error())
+			}
+			return
+		}
+	}
+}
+
+func (p *Proxy) backendStatus(plr *player.Player, backend Backend) (*protocol.StatusResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+
+	upstream, err := client.DialContext(ctx, backend.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer upstream.Close()
+
+	if protocol.IsSupportedVersion(plr.ProtocolVersion) {
+		upstream.ProtocolVersion = plr.ProtocolVersion
+	}
+	status, err := upstream.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &status.StatusResponse, nil
+}
+
+func (p *Proxy) handleLogin(plr *player.Player, backend Backend) {
+	if !protocol.IsSupportedVersion(plr.ProtocolVersion) {
+		p.disconnect(plr, "Unsupported client version! Please use 1.7.10 or 1.8")
+		return
+	}
+
+	codec, err := p.readPacket(plr)
+	if err != nil {
+		logReadError(err)
+		return
+	}
+	loginStart, ok := codec.(*protocol.LoginStartPacket)
+	if !ok {
+		slog.Error("Unexpected packet in login", "type", fmt.Sprintf("%T", codec))
+		return
+	}
+	plr.Name = loginStart.Name
+
+	upstream, result, err := p.connect(plr, backend)
+	if err != nil {
+		slog.Error("error connecting to backend", "name", plr.Name, "backend", backend.Name, "err", err.Error())
+
+		var disconnectErr *client.DisconnectError
+		if errors.As(err, &disconnectErr) {
+			err = p.sendPacket(plr, &protocol.LoginDisconnectPacket{Reason: disconnectErr.Reason})
+			if err != nil {
+				slog.Error("error sending login disconnect packet", "err", err.Error())
+			}
+			return
+		}
+		p.disconnect(plr, "Could not connect to "+backend.Name)
+		return
+	}
+
+	// the client gets the identity the backend gave the player
+	plr.UUID = result.UUID
+	plr.Name = result.Name
+	err = p.sendPacket(plr, &protocol.LoginSuccessPacket{
+		UUID:     plr.UUID.String(),
+		Username: plr.Name,
+	})
+	if err != nil {
+		slog.Error("error sending login success packet", "err", err.Error())
+		upstream.Close()
+		return
+	}
+	plr.State.SetState(fsm.FSMStatePlay)
+	plr.IsLoggedIn = true
+
+	slog.Info("Player connected", "name", plr.Name, "backend", backend.Name)
+
+	session := newSession(p, plr, backend, upstream)
+	session.run()
+}
+
+// connect logs plr in to backend, replaying the handshake and login start
+// the player sent.
+func (p *Proxy) connect(plr *player.Player, backend Backend) (*client.Client, *client.LoginResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+
+	upstream, err := client.DialContext(ctx, backend.Addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	upstream.ProtocolVersion = plr.ProtocolVersion
+
+	result, err := upstream.LoginContext(ctx, plr.Name)
+	if err != nil {
+		upstream.Close()
+		return nil, nil, err
+	}
+	return upstream, result, nil
+}
+
+func (p *Proxy) readPacket(plr *player.Player) (packet.Codec, error) {
+	pkt, err := plr.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	defer pkt.Release()
+	return protocol.Registry.Lookup(p.protocolVersion(plr), plr.State.State(), packet.Serverbound, pkt)
+}
+
+// protocolVersion is the version packets to and from plr are encoded with.
+func (p *Proxy) protocolVersion(plr *player.Player) int32 {
+	if protocol.IsSupportedVersion(plr.ProtocolVersion) {
+		return plr.ProtocolVersion
+	}
+	return protocol.Version1_7_10
+}
+
+// sendPacket encodes codec with the id it has in the player's current state.
+func (p *Proxy) sendPacket(plr *player.Player, codec packet.Codec) error {
+	pkt, err := protocol.Registry.Packet(p.protocolVersion(plr), plr.State.State(), packet.Clientbound, codec)
+	if err != nil {
+		return err
+	}
+	err = plr.SendPacket(pkt)
+	pkt.Release()
+	return err
+}
+
+// disconnect kicks a player that is still logging in.
+func (p *Proxy) disconnect(plr *player.Player, reason string) {
+	slog.Info("Disconnecting player", "name", plr.Name, "reason", reason)
+
+	disconnectPkt, err := protocol.NewLoginDisconnectPacket(chat.Text(reason))
+	if err != nil {
+		slog.Error("error creating login disconnect packet", "err", err.Error())
+		return
+	}
+	err = p.sendPacket(plr, disconnectPkt)
+	if err != nil {
+		slog.Error("error sending login disconnect packet", "err", err.Error())
+	}
+}
+
+func logReadError(err error) {
+	switch {
+	case isClosed(err):
+		// the peer went away, nothing to report
+	default:
+		slog.Error("Error reading packet", "err", err.Error())
+	}
+}
+
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/internal/mctest"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyLoginAndSwitch(t *testing.T) {
+	config := Config{
+		Backends: []Backend{
+			{Name: "lobby", Addr: mctest.StartServer(t, server.WithOfflineUUIDs(true))},
+			{Name: "survival", Addr: mctest.StartServer(t, server.WithOfflineUUIDs(true))},
+		},
+	}
+
+	sessions := make(chan *Session, 1)
+	respawns := make(chan int32, 2)
+	addr := mctest.FreeAddr(t)
+	go NewProxy(addr, config, WithHooks(Hooks{
+		JoinGame: func(s *Session, pkt *protocol.JoinGamePacket) {
+			select {
+			case sessions <- s:
+			default:
+			}
+		},
+		Respawn: func(s *Session, pkt *protocol.RespawnPacket) {
+			respawns <- pkt.Dimension
+		},
+	})).Listen()
+
+	c := mctest.Dial(t, addr)
+	defer c.Close()
+	result, err := c.Login("Notch")
+	assert.Nil(t, err)
+	assert.Equal(t, uuid.OfflineUUID("Notch"), result.UUID)
+
+	joinGame := mctest.Receive[*protocol.JoinGamePacket](t, c)
+	assert.Equal(t, "default", joinGame.LevelType)
+	mctest.Receive[*protocol.SpawnPositionPacket](t, c)
+
+	session := <-sessions
+	assert.Equal(t, "Notch", session.Name())
+	assert.Equal(t, "lobby", session.Backend().Name)
+
+	// packets are spliced both ways
+	err = c.Send(&protocol.PluginMessage{Channel: "MC|Brand", Data: []byte("vanilla")})
+	assert.Nil(t, err)
+	brand := mctest.Receive[*protocol.PluginMessage](t, c)
+	assert.Equal(t, []byte("vanilla"), brand.Data)
+
+	assert.ErrorIs(t, session.Switch("creative"), ErrUnknownBackend)
+	assert.Nil(t, session.Switch("survival"))
+	assert.Equal(t, "survival", session.Backend().Name)
+
+	first := mctest.Receive[*protocol.RespawnPacket](t, c)
+	second := mctest.Receive[*protocol.RespawnPacket](t, c)
+	assert.Equal(t, int32(-1), first.Dimension)
+	assert.Equal(t, int32(0), second.Dimension)
+	assert.Equal(t, int32(-1), <-respawns)
+	assert.Equal(t, int32(0), <-respawns)
+	mctest.Receive[*protocol.SpawnPositionPacket](t, c)
+}
+
+func TestPacketHookReplacement(t *testing.T) {
+	config := Config{Backends: []Backend{{Name: "lobby", Addr: mctest.StartServer(t, server.WithOfflineUUIDs(true))}}}
+
+	// the backend echoes the brand, which is rewritten on the way there and
+	// on the way back
+	addr := mctest.FreeAddr(t)
+	go NewProxy(addr, config, WithHooks(Hooks{
+		Packet: func(s *Session, direction packet.Direction, pkt *packet.Packet) *packet.Packet {
+			brandID := packet.IDServerPluginMessage
+			if direction == packet.Serverbound {
+				brandID = packet.IDClientPluginMessage
+			}
+			if pkt.ID() != brandID {
+				return pkt
+			}
+			codec, err := protocol.Registry.Lookup(s.ProtocolVersion(), fsm.FSMStatePlay, direction, pkt)
+			brand, ok := codec.(*protocol.PluginMessage)
+			if err != nil || !ok {
+				return nil
+			}
+			if direction == packet.Serverbound {
+				brand.Data = []byte("modded")
+			} else {
+				brand.Data = append(brand.Data, "+proxy"...)
+			}
+			replacement, err := protocol.Registry.Packet(s.ProtocolVersion(), fsm.FSMStatePlay, direction, brand)
+			assert.Nil(t, err)
+			return replacement
+		},
+	})).Listen()
+
+	c := mctest.Dial(t, addr)
+	defer c.Close()
+	_, err := c.Login("Notch")
+	assert.Nil(t, err)
+	mctest.Receive[*protocol.JoinGamePacket](t, c)
+
+	err = c.Send(&protocol.PluginMessage{Channel: "MC|Brand", Data: []byte("vanilla")})
+	assert.Nil(t, err)
+	brand := mctest.Receive[*protocol.PluginMessage](t, c)
+	assert.Equal(t, []byte("modded+proxy"), brand.Data)
+}
+
+func TestApplyPacketHookReleases(t *testing.T) {
+	var replacement *packet.Packet
+	s := &Session{proxy: &Proxy{hooks: Hooks{
+		Packet: func(s *Session, direction packet.Direction, pkt *packet.Packet) *packet.Packet {
+			switch pkt.ID() {
+			case 1:
+				return pkt
+			case 2:
+				replacement = packet.NewPacket(3)
+				return replacement
+			}
+			return nil
+		},
+	}}}
+
+	kept := packet.NewPacket(1)
+	assert.Equal(t, kept, s.applyPacketHook(packet.Clientbound, kept))
+	assert.NotNil(t, kept.Buffer())
+	kept.Release()
+
+	replaced := packet.NewPacket(2)
+	assert.Equal(t, replacement, s.applyPacketHook(packet.Serverbound, replaced))
+	assert.Nil(t, replaced.Buffer())
+	assert.NotNil(t, replacement.Buffer())
+	replacement.Release()
+
+	dropped := packet.NewPacket(4)
+	assert.Nil(t, s.applyPacketHook(packet.Clientbound, dropped))
+	assert.Nil(t, dropped.Buffer())
+}
+
+func TestRewriteEntityID(t *testing.T) {
+	s := &Session{clientEntityID: 1, serverEntityID: 42}
+	assert.Equal(t, int32(1), s.RewriteEntityID(42))
+	assert.Equal(t, int32(42), s.RewriteEntityID(1))
+	assert.Equal(t, int32(7), s.RewriteEntityID(7))
+}
+
+func TestConfigPick(t *testing.T) {
+	config := Config{
+		Backends: []Backend{
+			{Name: "lobby", Addr: "lobby:25565"},
+			{Name: "pvp", Addr: "pvp:25565"},
+		},
+		ForcedHosts: map[string]string{
+			"pvp.example.com": "pvp",
+			"old.example.com": "gone",
+		},
+	}
+
+	backend, err := config.Pick("play.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "lobby", backend.Name)
+
+	backend, err = config.Pick("PVP.example.com.\x00FML\x00")
+	assert.Nil(t, err)
+	assert.Equal(t, "pvp", backend.Name)
+
+	_, err = config.Pick("old.example.com")
+	assert.ErrorIs(t, err, ErrUnknownBackend)
+
+	_, err = Config{}.Pick("play.example.com")
+	assert.ErrorIs(t, err, ErrNoBackends)
+}
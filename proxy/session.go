@@ -0,0 +1,281 @@
+package proxy
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/client"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+var ErrSessionClosed = errors.New("session closed")
+
+// Session is a player in the play state, connected to one backend at a
+// time.
+type Session struct {
+	proxy  *Proxy
+	player *player.Player
+
+	mu       sync.Mutex
+	backend  Backend
+	upstream *client.Client
+	closed   bool
+	// joined is set once the client got its Join Game
+	joined         bool
+	clientEntityID int32
+	serverEntityID int32
+}
+
+func newSession(p *Proxy, plr *player.Player, backend Backend, upstream *client.Client) *Session {
+	return &Session{
+		proxy:    p,
+		player:   plr,
+		backend:  backend,
+		upstream: upstream,
+	}
+}
+
+func (s *Session) Name() string {
+	return s.player.Name
+}
+
+func (s *Session) UUID() uuid.UUID {
+	return s.player.UUID
+}
+
+func (s *Session) ProtocolVersion() int32 {
+	return s.player.ProtocolVersion
+}
+
+// Backend returns the backend the player is connected to.
+func (s *Session) Backend() Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.backend
+}
+
+// RewriteEntityID translates an entity id between the client and the
+// current backend. The client knows itself by the entity id of the first
+// Join Game it got, while a backend it was switched to may use another one,
+// so the two ids are swapped. Swapping works in both directions.
+func (s *Session) RewriteEntityID(id int32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch id {
+	case s.serverEntityID:
+		return s.clientEntityID
+	case s.clientEntityID:
+		return s.serverEntityID
+	}
+	return id
+}
+
+// Switch moves the player to the backend called name. The player stays on
+// the current backend if the new one cannot be joined.
+func (s *Session) Switch(name string) error {
+	backend, err := s.proxy.config.Backend(name)
+	if err != nil {
+		return err
+	}
+
+	upstream, _, err := s.proxy.connect(s.player, backend)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		upstream.Close()
+		return ErrSessionClosed
+	}
+	previous := s.upstream
+	s.upstream = upstream
+	s.backend = backend
+	s.mu.Unlock()
+
+	slog.Info("Player switched backend", "name", s.Name(), "backend", backend.Name)
+
+	previous.Close()
+	go s.readUpstream(upstream)
+	return nil
+}
+
+// Disconnect kicks the player off the proxy.
+func (s *Session) Disconnect(reason string) error {
+	slog.Info("Disconnecting player", "name", s.Name(), "reason", reason)
+
+	disconnectPkt, err := protocol.NewDisconnectPacket(chat.Text(reason))
+	if err != nil {
+		return err
+	}
+	err = s.proxy.sendPacket(s.player, disconnectPkt)
+	s.player.Close()
+	return err
+}
+
+// run splices packets until the client goes away.
+func (s *Session) run() {
+	go s.readUpstream(s.upstream)
+	s.readClient()
+
+	s.mu.Lock()
+	s.closed = true
+	upstream := s.upstream
+	s.mu.Unlock()
+
+	upstream.Close()
+	slog.Info("Connection Closed", "name", s.Name())
+}
+
+func (s *Session) readClient() {
+	for {
+		pkt, err := s.player.ReadPacket()
+		if err != nil {
+			logReadError(err)
+			return
+		}
+
+		pkt = s.applyPacketHook(packet.Serverbound, pkt)
+		if pkt == nil {
+			continue
+		}
+
+		s.mu.Lock()
+		upstream := s.upstream
+		s.mu.Unlock()
+
+		err = upstream.WritePacket(pkt)
+		pkt.Release()
+		if err != nil && !errors.Is(err, packet.ErrWriterClosed) {
+			slog.Error("error forwarding packet to backend", "name", s.Name(), "id", pkt.ID(), "err", err.Error())
+		}
+	}
+}
+
+// readUpstream forwards the packets of one backend connection until it is
+// closed.
+func (s *Session) readUpstream(upstream *client.Client) {
+	for {
+		pkt, err := upstream.ReadPacket()
+		if err != nil {
+			s.mu.Lock()
+			current := s.upstream == upstream && !s.closed
+			backend := s.backend
+			s.mu.Unlock()
+
+			if current {
+				// the backend went away, not the player
+				logReadError(err)
+				s.Disconnect("Lost connection to " + backend.Name)
+			}
+			return
+		}
+
+		err = s.forwardClientbound(pkt)
+		if err != nil {
+			slog.Error("error forwarding packet to client", "name", s.Name(), "id", pkt.ID(), "err", err.Error())
+			if errors.Is(err, packet.ErrQueueFull) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Session) forwardClientbound(pkt *packet.Packet) error {
+	switch pkt.ID() {
+	case packet.IDServerJoinGame:
+		defer pkt.Release()
+		codec, err := protocol.Registry.Lookup(s.ProtocolVersion(), fsm.FSMStatePlay, packet.Clientbound, pkt)
+		if err != nil {
+			return err
+		}
+		return s.handleJoinGame(codec.(*protocol.JoinGamePacket))
+	case packet.IDServerRespawn:
+		defer pkt.Release()
+		codec, err := protocol.Registry.Lookup(s.ProtocolVersion(), fsm.FSMStatePlay, packet.Clientbound, pkt)
+		if err != nil {
+			return err
+		}
+		return s.sendRespawn(codec.(*protocol.RespawnPacket))
+	}
+
+	pkt = s.applyPacketHook(packet.Clientbound, pkt)
+	if pkt == nil {
+		return nil
+	}
+	defer pkt.Release()
+	return s.player.SendPacket(pkt)
+}
+
+// applyPacketHook runs the Packet hook on pkt and returns the packet to
+// forward, which the caller releases once it is sent. pkt is released here
+// when the hook replaced or dropped it.
+func (s *Session) applyPacketHook(direction packet.Direction, pkt *packet.Packet) *packet.Packet {
+	if s.proxy.hooks.Packet == nil {
+		return pkt
+	}
+	forward := s.proxy.hooks.Packet(s, direction, pkt)
+	if forward != pkt {
+		pkt.Release()
+	}
+	return forward
+}
+
+// handleJoinGame forwards the first Join Game as is. The client cannot join
+// a second game on the same connection, so after a switch it is sent two
+// Respawn packets instead: the first to another dimension, which makes the
+// client unload the world of the previous backend, and the second to the
+// dimension of the new one.
+func (s *Session) handleJoinGame(pkt *protocol.JoinGamePacket) error {
+	if s.proxy.hooks.JoinGame != nil {
+		s.proxy.hooks.JoinGame(s, pkt)
+	}
+
+	s.mu.Lock()
+	first := !s.joined
+	s.joined = true
+	s.serverEntityID = pkt.EntityID
+	if first {
+		s.clientEntityID = pkt.EntityID
+	}
+	s.mu.Unlock()
+
+	if first {
+		return s.proxy.sendPacket(s.player, pkt)
+	}
+
+	dimension := int32(pkt.Dimension)
+	unload := int32(0)
+	if dimension == 0 {
+		unload = -1
+	}
+
+	for _, d := range []int32{unload, dimension} {
+		err := s.sendRespawn(&protocol.RespawnPacket{
+			Dimension:  d,
+			Difficulty: pkt.Difficulty,
+			// Respawn has no hardcore flag
+			GameMode:  pkt.GameMode &^ 0x8,
+			LevelType: pkt.LevelType,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Session) sendRespawn(pkt *protocol.RespawnPacket) error {
+	if s.proxy.hooks.Respawn != nil {
+		s.proxy.hooks.Respawn(s, pkt)
+	}
+	return s.proxy.sendPacket(s.player, pkt)
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"io"
 	"math"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/jnaraujo/mcprotocol/api/uuid"
 )
@@ -14,20 +17,53 @@ const (
 	continueBits = 0x80 // if there is more bytes after current byte
 )
 
+const (
+	// MaxStringLength is the longest string, in characters, the protocol
+	// allows.
+	MaxStringLength = 32767
+	// MaxNameLength is the longest player name.
+	MaxNameLength = 16
+)
+
 var (
-	ErrTooBig = errors.New("too big")
+	ErrTooBig         = errors.New("too big")
+	ErrStringTooLong  = errors.New("string too long")
+	ErrBytesTooLong   = errors.New("byte array too long")
+	ErrNegativeLength = errors.New("negative length")
 )
 
+// maxPooledBufferSize keeps the pool from holding on to the memory of the
+// occasional huge packet.
+const maxPooledBufferSize = 64 * 1024
+
 type Buffer struct {
 	data *bytes.Buffer
 }
 
+var bufferPool = sync.Pool{
+	New: func() any { return NewBuffer() },
+}
+
 func NewBuffer() *Buffer {
 	return &Buffer{
 		data: new(bytes.Buffer),
 	}
 }
 
+// GetBuffer returns an empty buffer from a pool. It is given back with
+// PutBuffer once nothing refers to its bytes anymore.
+func GetBuffer() *Buffer {
+	return bufferPool.Get().(*Buffer)
+}
+
+func PutBuffer(buf *Buffer) {
+	if buf.data.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.data.Reset()
+	bufferPool.Put(buf)
+}
+
 func NewBufferFrom(data []byte) *Buffer {
 	return &Buffer{
 		data: bytes.NewBuffer(data),
@@ -38,21 +74,36 @@ func (buf *Buffer) WriteByte(value byte) error {
 	return buf.data.WriteByte(value)
 }
 
+// ReadByte reads one byte. Running out of bytes means the packet was cut
+// short, so it fails with io.ErrUnexpectedEOF, like every other read.
 func (buf *Buffer) ReadByte() (byte, error) {
 	currentByte, err := buf.data.ReadByte()
 	if err != nil {
-		return 0, err
+		return 0, io.ErrUnexpectedEOF
 	}
 	return currentByte, nil
 }
 
+// next consumes the next n bytes. The returned slice is only valid until the
+// buffer is written to.
+func (buf *Buffer) next(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrNegativeLength
+	}
+	if n > buf.data.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.data.Next(n), nil
+}
+
+// ReadBytes reads exactly n bytes. A length larger than what is left in the
+// buffer fails without allocating it.
 func (buf *Buffer) ReadBytes(n int) ([]byte, error) {
-	readBuf := make([]byte, n)
-	_, err := buf.data.Read(readBuf)
+	b, err := buf.next(n)
 	if err != nil {
 		return []byte{}, err
 	}
-	return readBuf, nil
+	return bytes.Clone(b), nil
 }
 
 func (buf *Buffer) WriteVarInt(value int32) error {
@@ -71,11 +122,20 @@ func (buf *Buffer) WriteVarInt(value int32) error {
 }
 
 func (buf *Buffer) ReadVarInt() (int32, error) {
+	return ReadVarInt(buf)
+}
+
+// ReadVarInt decodes a VarInt from any byte source, so a packet length can be
+// read straight off a stream with the same rules used by Buffer.
+func ReadVarInt(r io.ByteReader) (int32, error) {
 	val := int32(0)
 	pos := int32(0)
 	for {
-		currentByte, err := buf.ReadByte()
+		currentByte, err := r.ReadByte()
 		if err != nil {
+			if pos > 0 && errors.Is(err, io.EOF) {
+				return 0, io.ErrUnexpectedEOF
+			}
 			return 0, err
 		}
 		val |= int32(currentByte&segmentBits) << pos
@@ -92,6 +152,9 @@ func (buf *Buffer) ReadVarInt() (int32, error) {
 }
 
 func (buf *Buffer) WriteString(str string) error {
+	if utf8.RuneCountInString(str) > MaxStringLength {
+		return ErrStringTooLong
+	}
 	err := buf.WriteVarInt(int32(len(str)))
 	if err != nil {
 		return err
@@ -100,24 +163,37 @@ func (buf *Buffer) WriteString(str string) error {
 	return err
 }
 
+// ReadString reads a string of at most MaxStringLength characters.
 func (buf *Buffer) ReadString() (string, error) {
+	return buf.ReadStringMax(MaxStringLength)
+}
+
+// ReadStringMax reads a string of at most max characters.
+func (buf *Buffer) ReadStringMax(max int) (string, error) {
 	length, err := buf.ReadVarInt()
 	if err != nil {
 		return "", err
 	}
+	if length < 0 {
+		return "", ErrNegativeLength
+	}
+	// a character takes up to 4 bytes
+	if int(length) > max*utf8.UTFMax {
+		return "", ErrStringTooLong
+	}
 
-	strBytes := make([]byte, length)
-	_, err = buf.data.Read(strBytes)
+	strBytes, err := buf.next(int(length))
 	if err != nil {
 		return "", err
 	}
-
+	if utf8.RuneCount(strBytes) > max {
+		return "", ErrStringTooLong
+	}
 	return string(strBytes), nil
 }
 
 func (buf *Buffer) ReadUShort() (uint16, error) {
-	b := make([]byte, 2)
-	_, err := buf.data.Read(b)
+	b, err := buf.next(2)
 	if err != nil {
 		return 0, err
 	}
@@ -125,15 +201,12 @@ func (buf *Buffer) ReadUShort() (uint16, error) {
 }
 
 func (buf *Buffer) WriteUShort(value uint16) error {
-	b := make([]byte, 2)
-	binary.BigEndian.PutUint16(b, value)
-	_, err := buf.data.Write(b)
+	_, err := buf.data.Write(binary.BigEndian.AppendUint16(buf.data.AvailableBuffer(), value))
 	return err
 }
 
 func (buf *Buffer) ReadShort() (int16, error) {
-	b := make([]byte, 2)
-	_, err := buf.data.Read(b)
+	b, err := buf.next(2)
 	if err != nil {
 		return 0, err
 	}
@@ -141,22 +214,17 @@ func (buf *Buffer) ReadShort() (int16, error) {
 }
 
 func (buf *Buffer) WriteShort(value int16) error {
-	b := make([]byte, 2)
-	binary.BigEndian.PutUint16(b, uint16(value))
-	_, err := buf.data.Write(b)
+	_, err := buf.data.Write(binary.BigEndian.AppendUint16(buf.data.AvailableBuffer(), uint16(value)))
 	return err
 }
 
 func (buf *Buffer) WriteLong(value int64) error {
-	b := make([]byte, 8)
-	binary.BigEndian.PutUint64(b, uint64(value))
-	_, err := buf.data.Write(b)
+	_, err := buf.data.Write(binary.BigEndian.AppendUint64(buf.data.AvailableBuffer(), uint64(value)))
 	return err
 }
 
 func (buf *Buffer) ReadLong() (int64, error) {
-	b := make([]byte, 8)
-	_, err := buf.data.Read(b)
+	b, err := buf.next(8)
 	if err != nil {
 		return 0, err
 	}
@@ -199,8 +267,7 @@ func (buf *Buffer) WriteVarLong(value int64) error {
 }
 
 func (buf *Buffer) ReadUUID() (uuid.UUID, error) {
-	b := make([]byte, 16)
-	_, err := buf.data.Read(b)
+	b, err := buf.next(16)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
@@ -233,15 +300,12 @@ func (buf *Buffer) WriteBytes(p []byte) (n int, err error) {
 
 // Signed 32-bit integer, two's complement
 func (buf *Buffer) WriteInt(value int32) error {
-	va := make([]byte, 4)
-	binary.BigEndian.PutUint32(va, uint32(value))
-	_, err := buf.data.Write(va)
+	_, err := buf.data.Write(binary.BigEndian.AppendUint32(buf.data.AvailableBuffer(), uint32(value)))
 	return err
 }
 
 func (buf *Buffer) ReadInt() (int32, error) {
-	b := make([]byte, 4)
-	_, err := buf.data.Read(b)
+	b, err := buf.next(4)
 	if err != nil {
 		return 0, err
 	}
@@ -249,15 +313,12 @@ func (buf *Buffer) ReadInt() (int32, error) {
 }
 
 func (buf *Buffer) WriteDouble(d float64) error {
-	b := make([]byte, 8)
-	binary.BigEndian.PutUint64(b, math.Float64bits(d))
-	_, err := buf.data.Write(b)
+	_, err := buf.data.Write(binary.BigEndian.AppendUint64(buf.data.AvailableBuffer(), math.Float64bits(d)))
 	return err
 }
 
 func (buf *Buffer) ReadDouble() (float64, error) {
-	b := make([]byte, 8)
-	_, err := buf.data.Read(b)
+	b, err := buf.next(8)
 	if err != nil {
 		return 0, err
 	}
@@ -265,6 +326,73 @@ func (buf *Buffer) ReadDouble() (float64, error) {
 	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
 }
 
+// ReadFull appends exactly n bytes read from r, growing the buffer once.
+func (buf *Buffer) ReadFull(r io.Reader, n int) error {
+	if n < 0 {
+		return ErrNegativeLength
+	}
+	buf.data.Grow(n)
+	b := buf.data.AvailableBuffer()[:n]
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return err
+	}
+	// b already is where the bytes go, this only moves the write offset
+	buf.data.Write(b)
+	return nil
+}
+
+func (buf *Buffer) WriteFloat(f float32) error {
+	_, err := buf.data.Write(binary.BigEndian.AppendUint32(buf.data.AvailableBuffer(), math.Float32bits(f)))
+	return err
+}
+
+func (buf *Buffer) ReadFloat() (float32, error) {
+	b, err := buf.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
+}
+
+// WriteSByte writes a signed byte. WriteByte is the unsigned one.
+func (buf *Buffer) WriteSByte(value int8) error {
+	return buf.WriteByte(byte(value))
+}
+
+func (buf *Buffer) ReadSByte() (int8, error) {
+	b, err := buf.ReadByte()
+	return int8(b), err
+}
+
+func (buf *Buffer) WriteUInt(value uint32) error {
+	_, err := buf.data.Write(binary.BigEndian.AppendUint32(buf.data.AvailableBuffer(), value))
+	return err
+}
+
+func (buf *Buffer) ReadUInt() (uint32, error) {
+	b, err := buf.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// WriteUUIDString writes id as a hyphenated string, the way 1.7 sends UUIDs
+// in Login Success and Spawn Player.
+func (buf *Buffer) WriteUUIDString(id uuid.UUID) error {
+	return buf.WriteString(id.String())
+}
+
+func (buf *Buffer) ReadUUIDString() (uuid.UUID, error) {
+	// 32 hex digits and 4 hyphens
+	s, err := buf.ReadStringMax(36)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return uuid.UUIDFromString(s)
+}
+
 func (buf *Buffer) Len() int {
 	return buf.data.Len()
 }
@@ -2,10 +2,14 @@ package raknet
 
 import (
 	"encoding/binary"
+	"io"
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/item"
+	"github.com/jnaraujo/mcprotocol/nbt"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -233,3 +237,332 @@ func TestWriteDouble(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, expected, actual)
 }
+
+func TestPosition(t *testing.T) {
+	for _, expected := range []Position{
+		{X: 0, Y: 0, Z: 0},
+		{X: 18357644, Y: 831, Z: -20882616},
+		{X: -1, Y: 255, Z: 1},
+		{X: -33554432, Y: -2048, Z: 33554431},
+	} {
+		buf := NewBuffer()
+		assert.Nil(t, buf.WritePosition(expected))
+		assert.Equal(t, 8, buf.Len())
+
+		actual, err := buf.ReadPosition()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestWritePosition(t *testing.T) {
+	buf := NewBuffer()
+	buf.WritePosition(Position{X: 1, Y: 2, Z: 3})
+
+	expected := make([]byte, 8)
+	binary.BigEndian.PutUint64(expected, 1<<38|2<<26|3)
+	assert.Equal(t, expected, buf.Bytes())
+}
+
+func TestTruncatedReads(t *testing.T) {
+	reads := map[string]func(buf *Buffer) error{
+		"byte":    func(buf *Buffer) error { _, err := buf.ReadByte(); return err },
+		"ushort":  func(buf *Buffer) error { _, err := buf.ReadUShort(); return err },
+		"short":   func(buf *Buffer) error { _, err := buf.ReadShort(); return err },
+		"int":     func(buf *Buffer) error { _, err := buf.ReadInt(); return err },
+		"long":    func(buf *Buffer) error { _, err := buf.ReadLong(); return err },
+		"double":  func(buf *Buffer) error { _, err := buf.ReadDouble(); return err },
+		"uuid":    func(buf *Buffer) error { _, err := buf.ReadUUID(); return err },
+		"varint":  func(buf *Buffer) error { _, err := buf.ReadVarInt(); return err },
+		"varlong": func(buf *Buffer) error { _, err := buf.ReadVarLong(); return err },
+		"string":  func(buf *Buffer) error { _, err := buf.ReadString(); return err },
+		"bytes":   func(buf *Buffer) error { _, err := buf.ReadBytes(2); return err },
+	}
+
+	for name, read := range reads {
+		// one byte is never enough, except for a byte
+		if name != "byte" {
+			err := read(NewBufferFrom([]byte{0x80}))
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF, name)
+		}
+		err := read(NewBuffer())
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF, name)
+	}
+}
+
+func TestTruncatedReadKeepsBuffer(t *testing.T) {
+	buf := NewBufferFrom([]byte{1, 2, 3})
+	_, err := buf.ReadInt()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 3, buf.Len())
+}
+
+func TestReadStringLimits(t *testing.T) {
+	buf := NewBuffer()
+	buf.WriteVarInt(-1)
+	_, err := buf.ReadString()
+	assert.ErrorIs(t, err, ErrNegativeLength)
+
+	// a huge length fails before anything is allocated
+	buf = NewBuffer()
+	buf.WriteVarInt(math.MaxInt32)
+	_, err = buf.ReadString()
+	assert.ErrorIs(t, err, ErrStringTooLong)
+
+	buf = NewBuffer()
+	buf.WriteVarInt(100)
+	buf.WriteBytes([]byte("short"))
+	_, err = buf.ReadString()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	buf = NewBuffer()
+	buf.WriteString("a name that is way too long")
+	_, err = buf.ReadStringMax(MaxNameLength)
+	assert.ErrorIs(t, err, ErrStringTooLong)
+
+	// the limit counts characters, not bytes
+	buf = NewBuffer()
+	buf.WriteString("ããããã")
+	actual, err := buf.ReadStringMax(5)
+	assert.Nil(t, err)
+	assert.Equal(t, "ããããã", actual)
+
+	err = NewBuffer().WriteString(strings.Repeat("a", MaxStringLength+1))
+	assert.ErrorIs(t, err, ErrStringTooLong)
+}
+
+func TestReadBytesLimits(t *testing.T) {
+	buf := NewBufferFrom([]byte{1, 2, 3})
+	_, err := buf.ReadBytes(-1)
+	assert.ErrorIs(t, err, ErrNegativeLength)
+	_, err = buf.ReadBytes(math.MaxInt32)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	actual, err := buf.ReadBytes(3)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, actual)
+}
+
+// BenchmarkPlayerPosition writes and reads the fields of a player position
+// packet, which clients send 20 times per second.
+func BenchmarkPlayerPosition(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		buf := GetBuffer()
+		buf.WriteDouble(1.5)
+		buf.WriteDouble(64)
+		buf.WriteDouble(65.62)
+		buf.WriteDouble(-3)
+		buf.WriteBool(true)
+
+		buf.ReadDouble()
+		buf.ReadDouble()
+		buf.ReadDouble()
+		buf.ReadDouble()
+		buf.ReadBool()
+		PutBuffer(buf)
+	}
+}
+
+func BenchmarkFixedWidth(b *testing.B) {
+	buf := NewBuffer()
+	b.ReportAllocs()
+	for range b.N {
+		buf.WriteShort(1)
+		buf.WriteInt(2)
+		buf.WriteLong(3)
+		buf.WriteUShort(4)
+		buf.ReadShort()
+		buf.ReadInt()
+		buf.ReadLong()
+		buf.ReadUShort()
+	}
+}
+
+func TestFloat(t *testing.T) {
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteFloat(-1.25))
+	assert.Equal(t, []byte{0xbf, 0xa0, 0x00, 0x00}, buf.Bytes())
+
+	actual, err := buf.ReadFloat()
+	assert.Nil(t, err)
+	assert.Equal(t, float32(-1.25), actual)
+}
+
+func TestSignedAndUnsigned(t *testing.T) {
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteSByte(-2))
+	assert.Nil(t, buf.WriteUInt(math.MaxUint32))
+	assert.Equal(t, []byte{0xfe, 0xff, 0xff, 0xff, 0xff}, buf.Bytes())
+
+	sb, err := buf.ReadSByte()
+	assert.Nil(t, err)
+	assert.Equal(t, int8(-2), sb)
+	ui, err := buf.ReadUInt()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(math.MaxUint32), ui)
+}
+
+func TestUUIDString(t *testing.T) {
+	expected, err := uuid.UUIDFromString("89ce1791-dab0-4b2a-97d9-ee72a0cdc1fd")
+	assert.Nil(t, err)
+
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteUUIDString(expected))
+	assert.Equal(t, 1+36, buf.Len())
+
+	actual, err := buf.ReadUUIDString()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+
+	buf = NewBuffer()
+	buf.WriteString("not a uuid")
+	_, err = buf.ReadUUIDString()
+	assert.NotNil(t, err)
+}
+
+func TestAngle(t *testing.T) {
+	buf := NewBuffer()
+	for _, degrees := range []float32{0, 90, 180, 270, -90, 360} {
+		assert.Nil(t, buf.WriteAngle(degrees))
+	}
+	assert.Equal(t, []byte{0, 64, 128, 192, 192, 0}, buf.Bytes())
+
+	for _, expected := range []float32{0, 90, 180, 270, 270, 0} {
+		actual, err := buf.ReadAngle()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestFixedPoint(t *testing.T) {
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteFixedPoint(-10.5))
+	assert.Nil(t, buf.WriteFixedPoint(64))
+	assert.Nil(t, buf.WriteFixedPointByte(1.25))
+	assert.Nil(t, buf.WriteFixedPointByte(-3.96875))
+	assert.ErrorIs(t, buf.WriteFixedPointByte(4), ErrTooBig)
+	assert.Equal(t, []byte{0xff, 0xff, 0xfe, 0xb0, 0, 0, 0x08, 0, 40, 0x81}, buf.Bytes())
+
+	for _, expected := range []float64{-10.5, 64} {
+		actual, err := buf.ReadFixedPoint()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+	for _, expected := range []float64{1.25, -3.96875} {
+		actual, err := buf.ReadFixedPointByte()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestSlot(t *testing.T) {
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteSlot(nil))
+	assert.Nil(t, buf.WriteSlot(&Slot{ID: 1, Count: 64}))
+	assert.Nil(t, buf.WriteSlot(&Slot{ID: 276, Count: 1, Damage: 12, NBT: []byte{0x1f, 0x8b}}))
+	assert.Equal(t, []byte{
+		0xff, 0xff,
+		0x00, 0x01, 64, 0x00, 0x00, 0xff, 0xff,
+		0x01, 0x14, 1, 0x00, 0x0c, 0x00, 0x02, 0x1f, 0x8b,
+	}, buf.Bytes())
+
+	for _, expected := range []*Slot{
+		nil,
+		{ID: 1, Count: 64},
+		{ID: 276, Count: 1, Damage: 12, NBT: []byte{0x1f, 0x8b}},
+	} {
+		actual, err := buf.ReadSlot()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	buf = NewBufferFrom([]byte{0x00, 0x01, 1, 0x00, 0x00, 0x00, 0x10, 0x1f})
+	_, err := buf.ReadSlot()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestMetadataHeader(t *testing.T) {
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteMetadataHeader(MetadataByte, 0))
+	assert.Nil(t, buf.WriteByte(0x02))
+	assert.Nil(t, buf.WriteMetadataHeader(MetadataFloat, 6))
+	assert.Nil(t, buf.WriteFloat(20))
+	assert.Nil(t, buf.WriteMetadataEnd())
+	assert.ErrorIs(t, buf.WriteMetadataHeader(MetadataByte, 32), ErrMetadataIndex)
+	assert.ErrorIs(t, buf.WriteMetadataHeader(MetadataFloat, 31), ErrMetadataIndex)
+
+	typ, index, end, err := buf.ReadMetadataHeader()
+	assert.Nil(t, err)
+	assert.False(t, end)
+	assert.Equal(t, MetadataByte, typ)
+	assert.Equal(t, byte(0), index)
+	buf.ReadByte()
+
+	typ, index, end, err = buf.ReadMetadataHeader()
+	assert.Nil(t, err)
+	assert.False(t, end)
+	assert.Equal(t, MetadataFloat, typ)
+	assert.Equal(t, byte(6), index)
+	buf.ReadFloat()
+
+	_, _, end, err = buf.ReadMetadataHeader()
+	assert.Nil(t, err)
+	assert.True(t, end)
+}
+
+func TestNBT(t *testing.T) {
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteNBT(nil))
+	assert.Nil(t, buf.WriteNBT(map[string]string{"name": "Bananrama"}))
+
+	var v map[string]string
+	ok, err := buf.ReadNBT(&v)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, v)
+
+	// the length prefix is a short
+	length := binary.BigEndian.Uint16(buf.Bytes())
+	assert.Equal(t, int(length), buf.Len()-2)
+
+	ok, err = buf.ReadNBT(&v)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "Bananrama"}, v)
+	assert.Equal(t, 0, buf.Len())
+
+	// a byte array of zeros compresses to nothing, but is too large to decode
+	assert.Nil(t, buf.WriteNBT(map[string][]byte{"data": make([]byte, nbt.MaxNetworkSize)}))
+	assert.Less(t, buf.Len(), 10<<10)
+	var large map[string][]byte
+	_, err = buf.ReadNBT(&large)
+	assert.ErrorIs(t, err, nbt.ErrTooLarge)
+}
+
+func TestItemStack(t *testing.T) {
+	sword := item.New(276, 1)
+	sword.Damage = 12
+	sword.SetDisplayName("Excalibur")
+
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteItemStack(nil))
+	assert.Nil(t, buf.WriteItemStack(item.New(1, 64)))
+	assert.Nil(t, buf.WriteItemStack(sword))
+
+	for _, expected := range []*item.ItemStack{nil, item.New(1, 64), sword} {
+		actual, err := buf.ReadItemStack()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// slots written with either type read back as the other
+	buf.WriteItemStack(sword)
+	slot, err := buf.ReadSlot()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b}, slot.NBT[:2])
+	buf.WriteSlot(slot)
+	stack, err := buf.ReadItemStack()
+	assert.Nil(t, err)
+	assert.Equal(t, sword, stack)
+}
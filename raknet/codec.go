@@ -0,0 +1,490 @@
+package raknet
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/jnaraujo/mcprotocol/item"
+)
+
+// Packet structs describe their wire format with `mc` field tags, so the
+// same declaration is used to read and to write them:
+//
+//	type LoginStartPacket struct {
+//		Name string `mc:"string,max=16"`
+//	}
+//
+// The first tag element is the wire type: byte, bool, short, ushort, int,
+// uint, long, varint, varlong, float, double, string, bytes, uuid,
+// uuidstring, position, angle, fixed, fixedbyte or slot. Options
+// follow it: max=N limits the length of a string or byte array, and
+// len=varint, len=short or len=int prefixes a byte array with its length
+// (without it, the array takes the rest of the buffer). Strings without max
+// are limited to MaxStringLength characters. Untagged fields use the wire
+// type that matches their Go type, nested structs are encoded field by field
+// and fields tagged `mc:"-"` are skipped. A Position is written as three
+// ints unless it is tagged `mc:"position"`, which packs it into a long.
+// A slot is either a *Slot, which keeps its NBT as is, or an
+// *item.ItemStack, whose NBT is decoded.
+//
+// When a field changed between protocol versions, a tag named after the
+// version it changed in overrides `mc` from that version on:
+//
+//	type KeepAlivePacket struct {
+//		ID int32 `mc:"int" mc47:"varint"`
+//	}
+//
+// WriteStruct and ReadStruct only look at `mc`, WriteStructVersion and
+// ReadStructVersion pick the tag for the given version.
+
+type fieldCodec struct {
+	index  int
+	name   string
+	kind   string
+	max    int
+	length string
+}
+
+type codecKey struct {
+	typ     reflect.Type
+	version int32
+}
+
+var structCodecs sync.Map // codecKey -> []fieldCodec
+
+var versionTagKey = regexp.MustCompile(`(?:^|\s)mc(\d+):"`)
+
+// fieldTag returns the tag of the newest version that is not newer than
+// version, falling back to `mc`.
+func fieldTag(tag reflect.StructTag, version int32) string {
+	value := tag.Get("mc")
+	best := int32(-1)
+	for _, match := range versionTagKey.FindAllStringSubmatch(string(tag), -1) {
+		tagVersion, err := strconv.ParseInt(match[1], 10, 32)
+		if err != nil || int32(tagVersion) > version || int32(tagVersion) <= best {
+			continue
+		}
+		best = int32(tagVersion)
+		value = tag.Get("mc" + match[1])
+	}
+	return value
+}
+
+func codecFor(t reflect.Type, version int32) ([]fieldCodec, error) {
+	key := codecKey{t, version}
+	if cached, ok := structCodecs.Load(key); ok {
+		return cached.([]fieldCodec), nil
+	}
+
+	var fields []fieldCodec
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := fieldTag(field.Tag, version)
+		if tag == "-" {
+			continue
+		}
+
+		fc := fieldCodec{
+			index: i,
+			name:  field.Name,
+		}
+
+		parts := strings.Split(tag, ",")
+		fc.kind = parts[0]
+		for _, opt := range parts[1:] {
+			key, value, _ := strings.Cut(opt, "=")
+			switch key {
+			case "max":
+				max, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: invalid max %q", field.Name, value)
+				}
+				fc.max = max
+			case "len":
+				fc.length = value
+			default:
+				return nil, fmt.Errorf("field %s: unknown option %q", field.Name, key)
+			}
+		}
+
+		if fc.kind == "" {
+			fc.kind = defaultKind(field.Type)
+			if fc.kind == "" {
+				return nil, fmt.Errorf("field %s: no wire type for %s", field.Name, field.Type)
+			}
+		}
+
+		fields = append(fields, fc)
+	}
+
+	structCodecs.Store(key, fields)
+	return fields, nil
+}
+
+var (
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	slotType = reflect.TypeOf(&Slot{})
+	itemType = reflect.TypeOf(&item.ItemStack{})
+)
+
+func defaultKind(t reflect.Type) string {
+	switch t {
+	case uuidType:
+		return "uuid"
+	case slotType, itemType:
+		return "slot"
+	}
+
+	switch t.Kind() {
+	case reflect.Uint8, reflect.Int8:
+		return "byte"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int16:
+		return "short"
+	case reflect.Uint16:
+		return "ushort"
+	case reflect.Int32:
+		return "int"
+	case reflect.Uint32:
+		return "uint"
+	case reflect.Int64:
+		return "long"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+	case reflect.Struct:
+		return "struct"
+	}
+	return ""
+}
+
+// WriteStruct writes the fields of v, a struct or a pointer to one, in
+// declaration order.
+func (buf *Buffer) WriteStruct(v any) error {
+	return buf.WriteStructVersion(v, 0)
+}
+
+// WriteStructVersion writes v the way the given protocol version expects it.
+func (buf *Buffer) WriteStructVersion(v any, version int32) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("raknet: WriteStruct needs a struct, got %T", v)
+	}
+	return buf.writeStruct(rv, version)
+}
+
+func (buf *Buffer) writeStruct(rv reflect.Value, version int32) error {
+	fields, err := codecFor(rv.Type(), version)
+	if err != nil {
+		return err
+	}
+
+	for _, fc := range fields {
+		err := buf.writeField(fc, rv.Field(fc.index), version)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", rv.Type().Name(), fc.name, err)
+		}
+	}
+	return nil
+}
+
+func (buf *Buffer) writeField(fc fieldCodec, v reflect.Value, version int32) error {
+	switch fc.kind {
+	case "byte":
+		if v.CanInt() {
+			return buf.WriteByte(byte(v.Int()))
+		}
+		return buf.WriteByte(byte(v.Uint()))
+	case "bool":
+		return buf.WriteBool(v.Bool())
+	case "short":
+		return buf.WriteShort(int16(v.Int()))
+	case "ushort":
+		return buf.WriteUShort(uint16(v.Uint()))
+	case "int":
+		return buf.WriteInt(int32(v.Int()))
+	case "uint":
+		return buf.WriteUInt(uint32(v.Uint()))
+	case "long":
+		return buf.WriteLong(v.Int())
+	case "varint":
+		return buf.WriteVarInt(int32(v.Int()))
+	case "varlong":
+		return buf.WriteVarLong(v.Int())
+	case "float":
+		return buf.WriteFloat(float32(v.Float()))
+	case "double":
+		return buf.WriteDouble(v.Float())
+	case "angle":
+		return buf.WriteAngle(float32(v.Float()))
+	case "fixed":
+		return buf.WriteFixedPoint(v.Float())
+	case "fixedbyte":
+		return buf.WriteFixedPointByte(v.Float())
+	case "string":
+		if fc.max > 0 && utf8.RuneCountInString(v.String()) > fc.max {
+			return ErrStringTooLong
+		}
+		return buf.WriteString(v.String())
+	case "bytes":
+		b := v.Bytes()
+		if fc.max > 0 && len(b) > fc.max {
+			return ErrBytesTooLong
+		}
+		err := buf.writeLength(fc.length, len(b))
+		if err != nil {
+			return err
+		}
+		_, err = buf.WriteBytes(b)
+		return err
+	case "uuid":
+		return buf.WriteUUID(v.Interface().(uuid.UUID))
+	case "uuidstring":
+		return buf.WriteUUIDString(v.Interface().(uuid.UUID))
+	case "slot":
+		if v.Type() == itemType {
+			return buf.WriteItemStack(v.Interface().(*item.ItemStack))
+		}
+		return buf.WriteSlot(v.Interface().(*Slot))
+	case "position":
+		return buf.WritePosition(v.Interface().(Position))
+	case "struct":
+		return buf.writeStruct(v, version)
+	}
+	return fmt.Errorf("unknown wire type %q", fc.kind)
+}
+
+func (buf *Buffer) writeLength(length string, n int) error {
+	switch length {
+	case "":
+		return nil
+	case "varint":
+		return buf.WriteVarInt(int32(n))
+	case "short":
+		return buf.WriteShort(int16(n))
+	case "int":
+		return buf.WriteInt(int32(n))
+	}
+	return fmt.Errorf("unknown length prefix %q", length)
+}
+
+// ReadStruct fills the fields of the struct v points to, in declaration
+// order.
+func (buf *Buffer) ReadStruct(v any) error {
+	return buf.ReadStructVersion(v, 0)
+}
+
+// ReadStructVersion reads v the way the given protocol version sends it.
+func (buf *Buffer) ReadStructVersion(v any, version int32) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("raknet: ReadStruct needs a pointer to a struct, got %T", v)
+	}
+	return buf.readStruct(rv.Elem(), version)
+}
+
+func (buf *Buffer) readStruct(rv reflect.Value, version int32) error {
+	fields, err := codecFor(rv.Type(), version)
+	if err != nil {
+		return err
+	}
+
+	for _, fc := range fields {
+		err := buf.readField(fc, rv.Field(fc.index), version)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", rv.Type().Name(), fc.name, err)
+		}
+	}
+	return nil
+}
+
+func (buf *Buffer) readField(fc fieldCodec, v reflect.Value, version int32) error {
+	switch fc.kind {
+	case "byte":
+		b, err := buf.ReadByte()
+		if err != nil {
+			return err
+		}
+		if v.CanInt() {
+			v.SetInt(int64(int8(b)))
+		} else {
+			v.SetUint(uint64(b))
+		}
+	case "bool":
+		b, err := buf.ReadBool()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case "short":
+		s, err := buf.ReadShort()
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(s))
+	case "ushort":
+		s, err := buf.ReadUShort()
+		if err != nil {
+			return err
+		}
+		v.SetUint(uint64(s))
+	case "int":
+		i, err := buf.ReadInt()
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case "uint":
+		i, err := buf.ReadUInt()
+		if err != nil {
+			return err
+		}
+		v.SetUint(uint64(i))
+	case "long":
+		l, err := buf.ReadLong()
+		if err != nil {
+			return err
+		}
+		v.SetInt(l)
+	case "varint":
+		i, err := buf.ReadVarInt()
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case "varlong":
+		l, err := buf.ReadVarLong()
+		if err != nil {
+			return err
+		}
+		v.SetInt(l)
+	case "float":
+		f, err := buf.ReadFloat()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(f))
+	case "double":
+		d, err := buf.ReadDouble()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(d)
+	case "angle":
+		a, err := buf.ReadAngle()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(a))
+	case "fixed":
+		f, err := buf.ReadFixedPoint()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case "fixedbyte":
+		f, err := buf.ReadFixedPointByte()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case "string":
+		max := MaxStringLength
+		if fc.max > 0 {
+			max = fc.max
+		}
+		s, err := buf.ReadStringMax(max)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case "bytes":
+		n, err := buf.readLength(fc.length)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return ErrNegativeLength
+		}
+		if fc.max > 0 && n > fc.max {
+			return ErrBytesTooLong
+		}
+		b, err := buf.ReadBytes(n)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+	case "uuid":
+		id, err := buf.ReadUUID()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(id))
+	case "uuidstring":
+		id, err := buf.ReadUUIDString()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(id))
+	case "slot":
+		if v.Type() == itemType {
+			stack, err := buf.ReadItemStack()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(stack))
+			return nil
+		}
+		slot, err := buf.ReadSlot()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(slot))
+	case "position":
+		p, err := buf.ReadPosition()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(p))
+	case "struct":
+		return buf.readStruct(v, version)
+	default:
+		return fmt.Errorf("unknown wire type %q", fc.kind)
+	}
+	return nil
+}
+
+func (buf *Buffer) readLength(length string) (int, error) {
+	switch length {
+	case "":
+		return buf.Len(), nil
+	case "varint":
+		n, err := buf.ReadVarInt()
+		return int(n), err
+	case "short":
+		n, err := buf.ReadShort()
+		return int(n), err
+	case "int":
+		n, err := buf.ReadInt()
+		return int(n), err
+	}
+	return 0, fmt.Errorf("unknown length prefix %q", length)
+}
@@ -0,0 +1,184 @@
+package raknet
+
+import (
+	"testing"
+
+	"github.com/jnaraujo/mcprotocol/api/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type codecState int32
+
+type codecInner struct {
+	X int32
+	Y int16
+}
+
+type codecStruct struct {
+	Version  int32      `mc:"varint"`
+	Name     string     `mc:"string,max=16"`
+	Port     uint16     // ushort
+	State    codecState `mc:"varint"`
+	Flag     bool
+	Signed   int8
+	Unsigned byte
+	Big      int64 `mc:"varlong"`
+	Time     int64
+	Ratio    float64
+	ID       uuid.UUID
+	Inner    codecInner
+	Secret   []byte `mc:"bytes,len=short"`
+	Token    []byte `mc:"bytes,len=varint"`
+	Ignored  string `mc:"-"`
+	Rest     []byte
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	id, err := uuid.UUIDFromString("89ce1791-dab0-4b2a-97d9-ee72a0cdc1fd")
+	assert.Nil(t, err)
+
+	expected := codecStruct{
+		Version:  5,
+		Name:     "Notch",
+		Port:     25565,
+		State:    2,
+		Flag:     true,
+		Signed:   -1,
+		Unsigned: 200,
+		Big:      -63412337812637,
+		Time:     9223372036854775807,
+		Ratio:    1.5,
+		ID:       id,
+		Inner:    codecInner{X: -7, Y: 300},
+		Secret:   []byte{1, 2, 3},
+		Token:    []byte{4, 5},
+		Ignored:  "not on the wire",
+		Rest:     []byte("the rest"),
+	}
+
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteStruct(&expected))
+
+	var actual codecStruct
+	assert.Nil(t, buf.ReadStruct(&actual))
+
+	expected.Ignored = ""
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestStructWireFormat(t *testing.T) {
+	buf := NewBuffer()
+	err := buf.WriteStruct(struct {
+		Length int32  `mc:"varint"`
+		Data   []byte `mc:"bytes,len=short"`
+		Port   uint16
+		Chunk  []byte `mc:"bytes,len=int"`
+	}{Length: 128, Data: []byte{9}, Port: 1200, Chunk: []byte{7, 8}})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []byte{0x80, 0x01, 0x00, 0x01, 0x09, 4, 176, 0, 0, 0, 2, 7, 8}, buf.Bytes())
+}
+
+func TestStructStringTooLong(t *testing.T) {
+	buf := NewBuffer()
+	err := buf.WriteStruct(struct {
+		Name string `mc:"string,max=16"`
+	}{Name: "a name that is way too long"})
+	assert.ErrorIs(t, err, ErrStringTooLong)
+
+	buf = NewBuffer()
+	buf.WriteString("a name that is way too long")
+	var actual struct {
+		Name string `mc:"string,max=16"`
+	}
+	assert.ErrorIs(t, buf.ReadStruct(&actual), ErrStringTooLong)
+}
+
+func TestReadStructNeedsPointer(t *testing.T) {
+	var actual codecInner
+	assert.NotNil(t, NewBuffer().ReadStruct(actual))
+}
+
+type versionedStruct struct {
+	EntityID  int32    `mc:"int" mc47:"varint"`
+	Location  Position `mc47:"position"`
+	HeadY     float64  `mc47:"-"`
+	SkinParts byte     `mc:"-" mc47:"byte"`
+	Data      []byte   `mc:"bytes,len=short" mc47:"bytes"`
+}
+
+func TestStructVersionTags(t *testing.T) {
+	expected := versionedStruct{
+		EntityID:  300,
+		Location:  Position{X: 1, Y: 64, Z: -1},
+		HeadY:     65.62,
+		SkinParts: 0x7F,
+		Data:      []byte{1, 2},
+	}
+
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteStructVersion(&expected, 5))
+	assert.Equal(t, 4+12+8+2+2, buf.Len())
+
+	var actual versionedStruct
+	assert.Nil(t, buf.ReadStructVersion(&actual, 5))
+	assert.Equal(t, versionedStruct{EntityID: 300, Location: expected.Location, HeadY: 65.62, Data: []byte{1, 2}}, actual)
+
+	buf = NewBuffer()
+	assert.Nil(t, buf.WriteStructVersion(&expected, 47))
+	assert.Equal(t, 2+8+1+2, buf.Len())
+
+	actual = versionedStruct{}
+	assert.Nil(t, buf.ReadStructVersion(&actual, 47))
+	assert.Equal(t, versionedStruct{EntityID: 300, Location: expected.Location, SkinParts: 0x7F, Data: []byte{1, 2}}, actual)
+
+	// versions after the override keep using it
+	buf = NewBuffer()
+	assert.Nil(t, buf.WriteStructVersion(&expected, 100))
+	assert.Equal(t, 2+8+1+2, buf.Len())
+}
+
+func TestStructNegativeLength(t *testing.T) {
+	buf := NewBuffer()
+	buf.WriteShort(-1)
+
+	var actual struct {
+		Data []byte `mc:"bytes,len=short"`
+	}
+	assert.ErrorIs(t, buf.ReadStruct(&actual), ErrNegativeLength)
+}
+
+type entityStruct struct {
+	ID      uuid.UUID `mc:"uuidstring"`
+	X       float64   `mc:"fixed"`
+	DX      float64   `mc:"fixedbyte"`
+	Yaw     float32   `mc:"angle"`
+	Health  float32
+	Flags   uint32
+	Held    *Slot
+	Offhand *Slot
+}
+
+func TestStructEntityPrimitives(t *testing.T) {
+	id, err := uuid.UUIDFromString("89ce1791-dab0-4b2a-97d9-ee72a0cdc1fd")
+	assert.Nil(t, err)
+
+	expected := entityStruct{
+		ID:     id,
+		X:      -10.5,
+		DX:     0.5,
+		Yaw:    90,
+		Health: 20,
+		Flags:  1 << 31,
+		Held:   &Slot{ID: 1, Count: 64},
+	}
+
+	buf := NewBuffer()
+	assert.Nil(t, buf.WriteStruct(&expected))
+	assert.Equal(t, 37+4+1+1+4+4+7+2, buf.Len())
+
+	var actual entityStruct
+	assert.Nil(t, buf.ReadStruct(&actual))
+	assert.Equal(t, expected, actual)
+}
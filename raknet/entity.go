@@ -0,0 +1,54 @@
+package raknet
+
+import "math"
+
+// fixedPointScale is how many steps a block is divided into by fixed-point
+// coordinates.
+const fixedPointScale = 32
+
+// WriteAngle writes an angle in degrees as a byte of 1/256 turn steps.
+func (buf *Buffer) WriteAngle(degrees float32) error {
+	steps := math.Round(float64(degrees) * 256 / 360)
+	return buf.WriteByte(byte(int64(steps)))
+}
+
+// ReadAngle reads an angle in degrees, from 0 up to but not including 360.
+func (buf *Buffer) ReadAngle() (float32, error) {
+	b, err := buf.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return float32(b) * 360 / 256, nil
+}
+
+// WriteFixedPoint writes a coordinate as an int of 1/32 block steps, the
+// way entity positions are sent before 1.9.
+func (buf *Buffer) WriteFixedPoint(v float64) error {
+	return buf.WriteInt(int32(math.Floor(v * fixedPointScale)))
+}
+
+func (buf *Buffer) ReadFixedPoint() (float64, error) {
+	i, err := buf.ReadInt()
+	if err != nil {
+		return 0, err
+	}
+	return float64(i) / fixedPointScale, nil
+}
+
+// WriteFixedPointByte writes a movement of less than 4 blocks as a signed
+// byte of 1/32 block steps, as used by Entity Relative Move.
+func (buf *Buffer) WriteFixedPointByte(v float64) error {
+	steps := math.Round(v * fixedPointScale)
+	if steps < math.MinInt8 || steps > math.MaxInt8 {
+		return ErrTooBig
+	}
+	return buf.WriteSByte(int8(steps))
+}
+
+func (buf *Buffer) ReadFixedPointByte() (float64, error) {
+	b, err := buf.ReadSByte()
+	if err != nil {
+		return 0, err
+	}
+	return float64(b) / fixedPointScale, nil
+}
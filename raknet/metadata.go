@@ -0,0 +1,60 @@
+package raknet
+
+import "errors"
+
+// Entity metadata is a list of entries, each starting with a header byte
+// that holds the type of the value in its top 3 bits and the index of the
+// entry in the other 5, and ends with MetadataEnd.
+
+// MetadataEnd marks the end of an entity metadata list.
+const MetadataEnd = 0x7F
+
+// MetadataType is the type of the value of a metadata entry.
+type MetadataType byte
+
+const (
+	MetadataByte MetadataType = iota
+	MetadataShort
+	MetadataInt
+	MetadataFloat
+	MetadataString
+	MetadataSlot
+	// MetadataVector is three ints
+	MetadataVector
+	// MetadataRotation is three floats, since 1.8
+	MetadataRotation
+)
+
+// maxMetadataIndex is the largest index that fits the header.
+const maxMetadataIndex = 0x1F
+
+var ErrMetadataIndex = errors.New("metadata index out of range")
+
+// WriteMetadataHeader starts a metadata entry. The value is written after
+// it with the writer of its type.
+func (buf *Buffer) WriteMetadataHeader(typ MetadataType, index byte) error {
+	header := byte(typ)<<5 | index
+	// a float at the last index would read as the end of the list
+	if index > maxMetadataIndex || typ > MetadataRotation || header == MetadataEnd {
+		return ErrMetadataIndex
+	}
+	return buf.WriteByte(header)
+}
+
+// WriteMetadataEnd ends a metadata list.
+func (buf *Buffer) WriteMetadataEnd() error {
+	return buf.WriteByte(MetadataEnd)
+}
+
+// ReadMetadataHeader reads the header of the next entry. end is set,
+// instead, when the list is over.
+func (buf *Buffer) ReadMetadataHeader() (typ MetadataType, index byte, end bool, err error) {
+	b, err := buf.ReadByte()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if b == MetadataEnd {
+		return 0, 0, true, nil
+	}
+	return MetadataType(b >> 5), b & maxMetadataIndex, false, nil
+}
@@ -0,0 +1,65 @@
+package raknet
+
+import (
+	"math"
+
+	"github.com/jnaraujo/mcprotocol/nbt"
+)
+
+// WriteNBT writes v the way 1.7.10 sends NBT in slots and block entity
+// updates: gzipped, prefixed by its length as a short. A nil v is written
+// as a length of -1.
+func (buf *Buffer) WriteNBT(v any) error {
+	if v == nil {
+		return buf.writeShortBytes(nil)
+	}
+	data, err := nbt.MarshalGzip(v)
+	if err != nil {
+		return err
+	}
+	return buf.writeShortBytes(data)
+}
+
+// ReadNBT reads NBT written by WriteNBT into v. It reports whether there was
+// any, leaving v untouched when there was not. Like vanilla, it gives up
+// with nbt.ErrTooLarge on documents over nbt.MaxNetworkSize once
+// decompressed.
+func (buf *Buffer) ReadNBT(v any) (bool, error) {
+	data, err := buf.readShortBytes()
+	if err != nil || data == nil {
+		return false, err
+	}
+	err = nbt.UnmarshalCompressedLimit(data, v, nbt.MaxNetworkSize)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeShortBytes writes p prefixed by its length as a short, -1 when p is
+// nil.
+func (buf *Buffer) writeShortBytes(p []byte) error {
+	if p == nil {
+		return buf.WriteShort(-1)
+	}
+	if len(p) > math.MaxInt16 {
+		return ErrBytesTooLong
+	}
+	err := buf.WriteShort(int16(len(p)))
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteBytes(p)
+	return err
+}
+
+func (buf *Buffer) readShortBytes() ([]byte, error) {
+	length, err := buf.ReadShort()
+	if err != nil {
+		return nil, err
+	}
+	if length == -1 {
+		return nil, nil
+	}
+	return buf.ReadBytes(int(length))
+}
@@ -0,0 +1,28 @@
+package raknet
+
+// Position is a block position. Before 1.8 it is sent as three separate
+// integers; since 1.8 it is packed into a single long.
+type Position struct {
+	X int32
+	Y int32
+	Z int32
+}
+
+// WritePosition packs p as x (26 bits), y (12 bits) and z (26 bits).
+func (buf *Buffer) WritePosition(p Position) error {
+	value := (int64(p.X)&0x3FFFFFF)<<38 | (int64(p.Y)&0xFFF)<<26 | int64(p.Z)&0x3FFFFFF
+	return buf.WriteLong(value)
+}
+
+func (buf *Buffer) ReadPosition() (Position, error) {
+	value, err := buf.ReadLong()
+	if err != nil {
+		return Position{}, err
+	}
+	// shift each part to the top first, so the sign is extended back down
+	return Position{
+		X: int32(value >> 38),
+		Y: int32(value << 26 >> 52),
+		Z: int32(value << 38 >> 38),
+	}, nil
+}
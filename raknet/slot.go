@@ -0,0 +1,103 @@
+package raknet
+
+import (
+	"github.com/jnaraujo/mcprotocol/item"
+	"github.com/jnaraujo/mcprotocol/nbt"
+)
+
+// emptySlotID is the item id of an empty slot.
+const emptySlotID = -1
+
+// Slot is an item stack as sent by 1.7.10. NBT holds the gzipped NBT
+// compound of the item as is, or nil when the item has none.
+type Slot struct {
+	ID     int16
+	Count  byte
+	Damage int16
+	NBT    []byte
+}
+
+// WriteSlot writes slot, or an empty slot when it is nil.
+func (buf *Buffer) WriteSlot(slot *Slot) error {
+	if slot == nil {
+		return buf.WriteShort(emptySlotID)
+	}
+
+	err := buf.WriteShort(slot.ID)
+	if err != nil {
+		return err
+	}
+	err = buf.WriteByte(slot.Count)
+	if err != nil {
+		return err
+	}
+	err = buf.WriteShort(slot.Damage)
+	if err != nil {
+		return err
+	}
+
+	return buf.writeShortBytes(slot.NBT)
+}
+
+// ReadSlot reads a slot, returning nil for an empty one.
+func (buf *Buffer) ReadSlot() (*Slot, error) {
+	id, err := buf.ReadShort()
+	if err != nil {
+		return nil, err
+	}
+	if id == emptySlotID {
+		return nil, nil
+	}
+
+	slot := &Slot{ID: id}
+	slot.Count, err = buf.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	slot.Damage, err = buf.ReadShort()
+	if err != nil {
+		return nil, err
+	}
+
+	slot.NBT, err = buf.readShortBytes()
+	if err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// WriteItemStack writes stack as a slot, or an empty slot when it is nil.
+func (buf *Buffer) WriteItemStack(stack *item.ItemStack) error {
+	if stack == nil {
+		return buf.WriteShort(emptySlotID)
+	}
+
+	slot := &Slot{ID: stack.ID, Count: stack.Count, Damage: stack.Damage}
+	if stack.NBT != nil {
+		data, err := nbt.MarshalGzip(stack.NBT)
+		if err != nil {
+			return err
+		}
+		slot.NBT = data
+	}
+	return buf.WriteSlot(slot)
+}
+
+// ReadItemStack reads a slot and decodes its NBT, returning nil for an empty
+// slot. Clients can send any slot, so the NBT is limited to
+// nbt.MaxNetworkSize like ReadNBT does.
+func (buf *Buffer) ReadItemStack() (*item.ItemStack, error) {
+	slot, err := buf.ReadSlot()
+	if err != nil || slot == nil {
+		return nil, err
+	}
+
+	stack := &item.ItemStack{ID: slot.ID, Count: slot.Count, Damage: slot.Damage}
+	if slot.NBT != nil {
+		err = nbt.UnmarshalCompressedLimit(slot.NBT, &stack.NBT, nbt.MaxNetworkSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stack, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/jnaraujo/mcprotocol/capture"
+	"github.com/jnaraujo/mcprotocol/packet"
+)
+
+// runReplay plays a capture back and returns the exit code. By default the
+// packets the client sent are replayed against a server; with --serve, the
+// packets the server sent are replayed to the first client that connects.
+func runReplay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:25565", "server to connect to, or address to listen on with --serve")
+	realtime := flags.Bool("realtime", false, "keep the timing of the recording")
+	serve := flags.Bool("serve", false, "replay the server side to a client")
+
+	paths, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(paths) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	records, err := readCapture(paths[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var conn net.Conn
+	direction := packet.Serverbound
+	if *serve {
+		direction = packet.Clientbound
+		conn, err = acceptOne(*addr)
+	} else {
+		conn, err = net.Dial("tcp", *addr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer conn.Close()
+
+	// whatever the other side answers is not checked, but it has to be
+	// read for it to keep going
+	go io.Copy(io.Discard, conn)
+
+	err = capture.Replay(conn, records, direction, *realtime)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "replayed %d %s packets\n", countRecords(records, direction), direction)
+	return 0
+}
+
+func readCapture(path string) ([]capture.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := capture.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r.ReadAll()
+}
+
+func acceptOne(addr string) (net.Conn, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	fmt.Fprintf(os.Stderr, "waiting for a client on %s\n", listener.Addr())
+	return listener.Accept()
+}
+
+func countRecords(records []capture.Record, direction packet.Direction) int {
+	n := 0
+	for _, rec := range records {
+		if rec.Direction == direction {
+			n++
+		}
+	}
+	return n
+}
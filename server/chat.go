@@ -0,0 +1,76 @@
+package server
+
+import (
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+// ChatHook is called with every chat message before it is broadcast. It
+// returns the message to broadcast, which may be rewritten, or false to
+// cancel it.
+type ChatHook func(plr *player.Player, message string) (chat.Component, bool)
+
+// FormatChat formats a message the way vanilla does, shown as
+// "<name> message".
+func FormatChat(name, message string) chat.Component {
+	return chat.Translate("chat.type.text", chat.Text(name), chat.Text(message))
+}
+
+// handleChat broadcasts a message typed by plr, or runs it if it is a
+// command.
+func (s *Server) handleChat(plr *player.Player, pkt *protocol.ClientChatMessagePacket) {
+	if utf8.RuneCountInString(pkt.Message) > protocol.MaxChatLength {
+		s.disconnect(plr, "Chat message too long")
+		return
+	}
+	message := strings.TrimSpace(pkt.Message)
+	if message == "" {
+		return
+	}
+	// § would let players format their messages, and clients never send
+	// control characters
+	if strings.ContainsFunc(message, func(r rune) bool {
+		return r == chat.SectionSign || unicode.IsControl(r)
+	}) {
+		s.disconnect(plr, "Illegal characters in chat")
+		return
+	}
+
+	if line, ok := strings.CutPrefix(message, "/"); ok {
+		s.handleCommand(plr, line)
+		return
+	}
+
+	slog.Info("Chat", "name", plr.Name, "message", message)
+
+	formatted, ok := FormatChat(plr.Name, message), true
+	if s.chatHook != nil {
+		formatted, ok = s.chatHook(plr, message)
+	}
+	if !ok {
+		return
+	}
+	s.Broadcast(formatted)
+}
+
+// Broadcast shows message in the chat of every player that is logged in.
+func (s *Server) Broadcast(message chat.Component) {
+	chatPkt, err := protocol.NewServerChatMessagePacket(message)
+	if err != nil {
+		slog.Error("error creating chat message packet", "err", err.Error())
+		return
+	}
+
+	for _, plr := range s.loggedInPlayers() {
+		err := s.sendPacket(plr, chatPkt)
+		if err != nil {
+			slog.Error("error sending chat message packet", "name", plr.Name, "err", err.Error())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/command"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+)
+
+// PermissionChecker decides whether plr has permission.
+type PermissionChecker func(plr *player.Player, permission string) bool
+
+// Commands returns the dispatcher running the commands players type, for
+// registering new ones.
+func (s *Server) Commands() *command.Dispatcher {
+	return s.commands
+}
+
+// sender runs commands on behalf of a player.
+type sender struct {
+	server *Server
+	plr    *player.Player
+}
+
+func (s *Server) sender(plr *player.Player) *sender {
+	return &sender{server: s, plr: plr}
+}
+
+func (snd *sender) Name() string {
+	return snd.plr.Name
+}
+
+// HasPermission asks the PermissionChecker. Without one, only commands that
+// need no permission can be run.
+func (snd *sender) HasPermission(permission string) bool {
+	if snd.server.permissions == nil {
+		return false
+	}
+	return snd.server.permissions(snd.plr, permission)
+}
+
+func (snd *sender) SendMessage(message chat.Component) error {
+	chatPkt, err := protocol.NewServerChatMessagePacket(message)
+	if err != nil {
+		return err
+	}
+	return snd.server.sendPacket(snd.plr, chatPkt)
+}
+
+func (snd *sender) Position() command.Vec3 {
+	return command.Vec3{X: snd.plr.Position.X, Y: snd.plr.Position.FeetY, Z: snd.plr.Position.Z}
+}
+
+// PlayerOf returns the player behind a command sender, like the value of a
+// command.Player argument.
+func PlayerOf(snd command.Sender) (*player.Player, bool) {
+	s, ok := snd.(*sender)
+	if !ok {
+		return nil, false
+	}
+	return s.plr, true
+}
+
+// onlineSenders lists the players that are logged in, for command.Player
+// arguments.
+func (s *Server) onlineSenders() []command.Sender {
+	players := s.loggedInPlayers()
+	senders := make([]command.Sender, len(players))
+	for i, plr := range players {
+		senders[i] = s.sender(plr)
+	}
+	return senders
+}
+
+// handleCommand runs line, a chat message without its leading slash.
+func (s *Server) handleCommand(plr *player.Player, line string) {
+	slog.Info("Command", "name", plr.Name, "command", line)
+
+	err := s.commands.Execute(s.sender(plr), line)
+	if err != nil {
+		slog.Info("Command failed", "name", plr.Name, "command", line, "err", err.Error())
+	}
+}
+
+// handleTabComplete suggests commands and their arguments, or player names
+// when the player is typing a chat message.
+func (s *Server) handleTabComplete(plr *player.Player, pkt *protocol.ClientTabCompletePacket) {
+	var matches []string
+	if line, ok := strings.CutPrefix(pkt.Text, "/"); ok {
+		matches = s.commands.Complete(s.sender(plr), line)
+		// the command name itself is replaced with its slash
+		if !strings.Contains(line, " ") {
+			for i, match := range matches {
+				matches[i] = "/" + match
+			}
+		}
+	} else {
+		words := strings.Split(pkt.Text, " ")
+		matches = s.commands.PlayerNames(words[len(words)-1])
+	}
+
+	err := s.sendPacket(plr, &protocol.ServerTabCompletePacket{Matches: matches})
+	if err != nil {
+		slog.Error("error sending tab complete packet", "err", err.Error())
+	}
+}
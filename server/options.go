@@ -0,0 +1,61 @@
+package server
+
+import "github.com/jnaraujo/mcprotocol/auth"
+
+type Option func(*Server)
+
+// WithOnlineMode makes players go through the encryption handshake and have
+// their session verified before they are let in.
+func WithOnlineMode(enabled bool) Option {
+	return func(s *Server) {
+		s.onlineMode = enabled
+	}
+}
+
+// WithSessionVerifier replaces the session server used to verify players in
+// online mode.
+func WithSessionVerifier(verifier auth.SessionVerifier) Option {
+	return func(s *Server) {
+		s.sessionVerifier = verifier
+	}
+}
+
+// WithOfflineUUIDs gives players in offline mode the same UUID vanilla
+// servers would, derived from their name, instead of a random one.
+func WithOfflineUUIDs(enabled bool) Option {
+	return func(s *Server) {
+		s.offlineUUIDs = enabled
+	}
+}
+
+// WithCaptureDir records the packets of every connection to a capture file
+// in dir, named after the time it was opened and the client address.
+func WithCaptureDir(dir string) Option {
+	return func(s *Server) {
+		s.captureDir = dir
+	}
+}
+
+// WithPermissions decides which commands players can run. Without it, only
+// commands that need no permission can be run.
+func WithPermissions(checker PermissionChecker) Option {
+	return func(s *Server) {
+		s.permissions = checker
+	}
+}
+
+// WithChatHook lets hook rewrite or cancel chat messages before they are
+// broadcast.
+func WithChatHook(hook ChatHook) Option {
+	return func(s *Server) {
+		s.chatHook = hook
+	}
+}
+
+// WithViewDistance sets how many columns around them players are sent at
+// most, in every direction. Players asking for less get less.
+func WithViewDistance(distance int) Option {
+	return func(s *Server) {
+		s.viewDistance = distance
+	}
+}
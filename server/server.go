@@ -1,55 +1,102 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"math"
 	"math/rand"
 	"net"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/jnaraujo/mcprotocol/api/uuid"
 	"github.com/jnaraujo/mcprotocol/auth"
+	"github.com/jnaraujo/mcprotocol/capture"
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/command"
 	"github.com/jnaraujo/mcprotocol/fsm"
 	"github.com/jnaraujo/mcprotocol/packet"
 	"github.com/jnaraujo/mcprotocol/player"
 	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/raknet"
+)
+
+// serverID is sent in the encryption request and hashed for session
+// verification. Clients since 1.7 accept an empty one.
+const serverID = ""
+
+const sessionVerifyTimeout = 10 * time.Second
+
+const (
+	// legacyPingDrainTimeout bounds how long the rest of a legacy ping is
+	// read after it has been answered.
+	legacyPingDrainTimeout = time.Second
+	// maxLegacyPingSize is more than any legacy ping takes: the one of 1.6
+	// clients, the longest, is the hostname and a few dozen bytes.
+	maxLegacyPingSize = 1024
 )
 
 type Server struct {
 	addr           string
 	statusResponse protocol.StatusResponse
-
-	crypto  *auth.Crypto
-	players map[string]*player.Player
+	onlineMode     bool
+	offlineUUIDs   bool
+	// captureDir is where connections are recorded, if set
+	captureDir string
+
+	sessionVerifier auth.SessionVerifier
+	chatHook        ChatHook
+	commands        *command.Dispatcher
+	permissions     PermissionChecker
+	viewDistance    int
+
+	crypto *auth.Crypto
+
+	// playersMu guards players and the IsLoggedIn flag of each of them,
+	// which are used by the goroutines of other players
+	playersMu sync.Mutex
+	players   map[string]*player.Player
 }
 
-func NewServer(addr string) *Server {
+func NewServer(addr string, opts ...Option) *Server {
 	crypto, err := auth.NewCrypto()
 	if err != nil {
 		panic(err)
 	}
 
-	return &Server{
-		addr:    addr,
-		crypto:  crypto,
-		players: make(map[string]*player.Player),
+	s := &Server{
+		addr:            addr,
+		crypto:          crypto,
+		sessionVerifier: auth.NewSessionVerifier(auth.DefaultSessionServerURL),
+		players:         make(map[string]*player.Player),
+		commands:        command.NewDispatcher(),
+		viewDistance:    defaultViewDistance,
 		statusResponse: protocol.StatusResponse{
 			Version: protocol.StatusResponseVersion{
-				Name:     "1.7.10",
-				Protocol: 5,
-			},
-			Description: protocol.StatusResponseDescription{
-				Text: "Hello, world!",
+				Name:     "1.7.10/1.8",
+				Protocol: int(protocol.Version1_7_10),
 			},
+			Description: chat.Text("Hello, world!"),
 			Players: protocol.StatusResponsePlayers{
 				Online: 0,
 				Max:    20,
 			},
 		},
 	}
+	s.commands.Players = s.onlineSenders
+	s.commands.Register(command.HelpCommand(s.commands))
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Server) Listen() error {
@@ -70,15 +117,10 @@ func (s *Server) Listen() error {
 
 			slog.Info("Sending KeepAlive packets", "id", rndId)
 
-			pkt := packet.NewPacket(packet.IDServerKeepAlive)
-			pkt.Buffer().WriteInt(rndId)
-			for addr, plr := range s.players {
-				if !plr.IsLoggedIn {
-					continue
-				}
-				err := plr.SendPacket(pkt)
+			for _, plr := range s.loggedInPlayers() {
+				err := s.sendPacket(plr, &protocol.KeepAlivePacket{ID: rndId})
 				if err != nil {
-					slog.Error("error sending keep alive packet", "addr", addr, "err", err.Error())
+					slog.Error("error sending keep alive packet", "addr", plr.Conn.RemoteAddr().String(), "err", err.Error())
 				}
 			}
 		}
@@ -93,227 +135,413 @@ func (s *Server) Listen() error {
 	}
 }
 
-func (s *Server) handleConnection(conn *net.TCPConn) {
+func (s *Server) handleConnection(conn net.Conn) {
 	slog.Info("New connection", "addr", conn.RemoteAddr().String())
 
+	s.playersMu.Lock()
 	plr, exists := s.players[conn.RemoteAddr().String()]
 	if !exists {
-		plr = &player.Player{
-			Conn: conn,
-		}
+		plr = player.NewPlayer(conn)
 		s.players[conn.RemoteAddr().String()] = plr
 	}
+	s.playersMu.Unlock()
+
+	if s.captureDir != "" {
+		rec, err := s.startCapture(conn)
+		if err != nil {
+			slog.Error("error creating capture file", "err", err.Error())
+		} else {
+			plr.SetRecorder(rec)
+			defer func() {
+				err := rec.Close()
+				if err != nil {
+					slog.Error("error writing capture file", "err", err.Error())
+				}
+			}()
+		}
+	}
 
 	// close player connection
 	defer s.closeConn(plr)
 
-	buf := make([]byte, packet.MaxPacketSizeInBytes)
+	first, err := plr.PeekByte()
+	if err == nil && first == protocol.LegacyPingID {
+		s.handleLegacyPing(plr)
+		return
+	}
+
 	for {
-		n, err := conn.Read(buf)
+		pkt, err := plr.ReadPacket()
 		if err != nil {
 			switch {
 			case errors.Is(err, net.ErrClosed),
 				errors.Is(err, io.EOF),
-				errors.Is(err, syscall.EPIPE):
-				return
+				errors.Is(err, syscall.EPIPE),
+				errors.Is(err, syscall.ECONNRESET):
+				// the client went away, nothing to report
 			default:
-				slog.Error("Error reading from connection", "err", err.Error())
-				continue
+				slog.Error("Error reading packet", "err", err.Error())
 			}
+			return
 		}
 
-		pkt := new(packet.Packet)
-		err = pkt.UnmarshalBinary(buf[:n])
+		codec, err := protocol.Registry.Lookup(s.protocolVersion(plr), plr.State.State(), packet.Serverbound, pkt)
+		// the decoded packet does not refer to the buffer
+		pkt.Release()
 		if err != nil {
-			slog.Error("Error unmarshalling packet", "err", err.Error())
-			return
+			if errors.Is(err, packet.ErrUnknownPacket) {
+				slog.Error("Packet not implemented yet", "id", pkt.ID(), "state", plr.State.State())
+			} else {
+				slog.Error("Error decoding packet", "id", pkt.ID(), "state", plr.State.State(), "err", err.Error())
+			}
+			continue
 		}
 
 		switch plr.State.State() {
 		case fsm.FSMStateHandshake:
-			s.handleHandshakeState(plr, pkt)
+			s.handleHandshakeState(plr, codec)
 		case fsm.FSMStateStatus:
-			s.handleStatusState(plr, pkt)
+			s.handleStatusState(plr, codec)
 		case fsm.FSMStateLogin:
-			s.handleLoginState(plr, pkt)
+			s.handleLoginState(plr, codec)
 		case fsm.FSMStatePlay:
-			s.handlePlayState(plr, pkt)
+			s.handlePlayState(plr, codec)
 		default:
-			slog.Error("State not implemented", "id", pkt.ID(), "size", n, "state", plr.State.State())
+			slog.Error("State not implemented", "id", pkt.ID(), "state", plr.State.State())
 		}
 	}
 }
 
-func (s *Server) handleHandshakeState(plr *player.Player, pkt *packet.Packet) {
-	handshakePkt, err := protocol.ReceiveHandshakePacket(pkt)
+// startCapture creates the capture file of a new connection.
+func (s *Server) startCapture(conn net.Conn) (*capture.Writer, error) {
+	name := fmt.Sprintf("%s-%s.mccap",
+		time.Now().Format("20060102-150405.000"),
+		strings.NewReplacer(":", "_", "[", "", "]", "").Replace(conn.RemoteAddr().String()),
+	)
+	return capture.Create(filepath.Join(s.captureDir, name))
+}
+
+// protocolVersion is the version packets to and from plr are encoded with.
+// The handshake and status packets are the same in every version, so until
+// the client says otherwise, or when it speaks a version we don't, they are
+// handled as 1.7.10.
+func (s *Server) protocolVersion(plr *player.Player) int32 {
+	if protocol.IsSupportedVersion(plr.ProtocolVersion) {
+		return plr.ProtocolVersion
+	}
+	return protocol.Version1_7_10
+}
+
+// sendPacket encodes codec with the id it has in the player's current state.
+func (s *Server) sendPacket(plr *player.Player, codec packet.Codec) error {
+	pkt, err := protocol.Registry.Packet(s.protocolVersion(plr), plr.State.State(), packet.Clientbound, codec)
 	if err != nil {
-		slog.Error("Error reading handshake", "err", err.Error())
+		return err
+	}
+	err = plr.SendPacket(pkt)
+	pkt.Release()
+	return err
+}
+
+// sendPackets sends codecs to plr together, see player.Player.SendPackets.
+func (s *Server) sendPackets(plr *player.Player, codecs []packet.Codec) error {
+	pkts := make([]*packet.Packet, 0, len(codecs))
+	defer func() {
+		for _, pkt := range pkts {
+			pkt.Release()
+		}
+	}()
+	for _, codec := range codecs {
+		pkt, err := protocol.Registry.Packet(s.protocolVersion(plr), plr.State.State(), packet.Clientbound, codec)
+		if err != nil {
+			return err
+		}
+		pkts = append(pkts, pkt)
+	}
+	return plr.SendPackets(pkts)
+}
+
+func (s *Server) handleHandshakeState(plr *player.Player, pkt packet.Codec) {
+	handshakePkt, ok := pkt.(*protocol.HandshakePacket)
+	if !ok {
+		slog.Error("Unexpected packet in handshake", "type", fmt.Sprintf("%T", pkt))
 		return
 	}
 
-	slog.Info("New HandShake Packet", "nextState", handshakePkt.NextState)
+	slog.Info("New HandShake Packet", "nextState", handshakePkt.NextState, "protocol", handshakePkt.ProtocolVersion)
+
+	plr.ProtocolVersion = handshakePkt.ProtocolVersion
 
 	switch handshakePkt.NextState {
 	case protocol.HandshakeNextStateStatus:
 		plr.State.SetState(fsm.FSMStateStatus)
+	case protocol.HandshakeNextStateLogin:
+		plr.State.SetState(fsm.FSMStateLogin)
+		if !protocol.IsSupportedVersion(plr.ProtocolVersion) {
+			s.disconnect(plr, "Unsupported client version! Please use 1.7.10 or 1.8")
+		}
+	default:
+		slog.Error("next state not implemented", "state", handshakePkt.NextState)
+	}
+}
+
+func (s *Server) handleStatusState(plr *player.Player, pkt packet.Codec) {
+	switch pkt := pkt.(type) {
+	case *protocol.StatusRequestPacket:
 		// show motd
-		statusRespPkt, err := protocol.CreateStatusResponsePacket(s.statusResponse)
+		statusResponse := s.statusResponse
+		if protocol.IsSupportedVersion(plr.ProtocolVersion) {
+			// tell the client we speak its version
+			statusResponse.Version.Protocol = int(plr.ProtocolVersion)
+		}
+
+		statusRespPkt, err := protocol.NewStatusResponsePacket(statusResponse)
 		if err != nil {
 			slog.Error("Error creating status response packet", "err", err.Error())
 			return
 		}
 
-		err = plr.SendPacket(statusRespPkt)
+		err = s.sendPacket(plr, statusRespPkt)
 		if err != nil {
 			slog.Error("Error sending status response bytes", "err", err.Error())
 			return
 		}
-
-	case protocol.HandshakeNextStateLogin:
-		plr.State.SetState(fsm.FSMStateLogin)
-	default:
-		slog.Error("next state not implemented", "state", handshakePkt.NextState)
+	case *protocol.PingRequestPacket:
+		err := s.sendPacket(plr, &protocol.PingResponsePacket{Payload: pkt.Payload})
+		if err != nil {
+			slog.Error("error sending ping response packet", "err", err.Error())
+			return
+		}
 	}
 }
 
-func (s *Server) handleStatusState(plr *player.Player, pkt *packet.Packet) {
-	if pkt.Buffer().Len() == 0 {
-		return
-	}
-
-	pingReqPkt, err := protocol.ReceivePingRequestPacket(pkt)
+// handleLegacyPing answers the server list ping of clients older than 1.7.
+// The answer is not a framed packet, so it is written straight to the
+// connection, which is closed afterwards.
+//
+// How much a legacy client sends depends on its version, so the request is
+// not parsed: it is answered right away and what is left of it is read and
+// thrown away until the client hangs up. Closing with unread data would make
+// the kernel reset the connection, and some clients then lose the answer.
+func (s *Server) handleLegacyPing(plr *player.Player) {
+	slog.Info("New legacy ping", "addr", plr.Conn.RemoteAddr().String())
+
+	_, err := plr.Conn.Write(protocol.NewLegacyPingResponse(s.statusResponse))
 	if err != nil {
-		slog.Error("Error receiving ping request packet", "err", err.Error())
+		slog.Error("error sending legacy ping response", "err", err.Error())
 		return
 	}
 
-	pingRespPkt, err := protocol.CreatePingResponsePacket(pingReqPkt.Payload)
-	if err != nil {
-		slog.Error("Error creating ping response packet", "err", err.Error())
-		return
-	}
-
-	err = plr.SendPacket(pingRespPkt)
-	if err != nil {
-		slog.Error("error sending ping response packet", "err", err.Error())
-		return
-	}
+	plr.Conn.SetReadDeadline(time.Now().Add(legacyPingDrainTimeout))
+	io.CopyN(io.Discard, plr.Conn, maxLegacyPingSize)
 }
 
-func (s *Server) handleLoginState(plr *player.Player, pkt *packet.Packet) {
-	slog.Info("New Login Packet", "id", pkt.ID())
-
-	switch pkt.ID() {
-	case 0x00: // login start
-		loginStartPkt, err := protocol.ReceiveLoginStartPacket(pkt)
-		if err != nil {
-			slog.Error("error receiving login start packet", "err", err.Error())
-			return
-		}
-
-		slog.Info("Hello, Player!", "name", loginStartPkt.Name)
+func (s *Server) handleLoginState(plr *player.Player, pkt packet.Codec) {
+	slog.Info("New Login Packet", "type", fmt.Sprintf("%T", pkt))
 
-		plr.Name = loginStartPkt.Name
-		plr.UUID = uuid.GenerateUUID() // generating a random UUID for now
-		plr.IsLoggedIn = true
+	switch pkt := pkt.(type) {
+	case *protocol.LoginStartPacket:
+		slog.Info("Hello, Player!", "name", pkt.Name)
 
-		// TODO: implement encryption!!!
+		plr.Name = pkt.Name
 
-		loginSuccessPkt, err := protocol.CreateLoginSuccessPacket(plr.UUID, loginStartPkt.Name)
-		if err != nil {
-			slog.Error("error creating login success packet", "err", err.Error())
+		if !s.onlineMode {
+			if s.offlineUUIDs {
+				plr.UUID = uuid.OfflineUUID(plr.Name)
+			} else {
+				plr.UUID = uuid.GenerateUUID()
+			}
+			s.finishLogin(plr)
 			return
 		}
-		err = plr.SendPacket(loginSuccessPkt)
+
+		var err error
+		plr.VerifyToken, err = auth.GenerateVerifyToken()
 		if err != nil {
-			slog.Error("error sending login success packet", "err", err.Error())
+			slog.Error("error generating verify token", "err", err.Error())
 			return
 		}
 
-		joinGamePkt, err := protocol.CreateJoinGamePacket()
+		err = s.sendPacket(plr, &protocol.EncryptionRequestPacket{
+			ServerID:    serverID,
+			PublicKey:   s.crypto.PublicKeyBytes(),
+			VerifyToken: plr.VerifyToken,
+		})
 		if err != nil {
-			slog.Error("error creating join game packet", "err", err.Error())
+			slog.Error("error sending encryption request packet", "err", err.Error())
 			return
 		}
-		err = plr.SendPacket(joinGamePkt)
-		if err != nil {
-			slog.Error("Error sending join game packet")
+	case *protocol.EncryptionResponsePacket:
+		if plr.VerifyToken == nil {
+			s.disconnect(plr, "Unexpected encryption response")
 			return
 		}
 
-		// change the state to game mode
-		plr.State.SetState(fsm.FSMStatePlay)
+		verifyToken, err := s.crypto.Decrypt(pkt.VerifyToken)
+		if err != nil || !bytes.Equal(verifyToken, plr.VerifyToken) {
+			s.disconnect(plr, "Invalid verify token")
+			return
+		}
+		plr.VerifyToken = nil
 
-		// send the spawn position
-		spawnPositionPkt, err := protocol.CreateSpawnPositionPacket()
+		sharedSecret, err := s.crypto.Decrypt(pkt.SharedSecret)
 		if err != nil {
-			slog.Error("error creating spawn position packet", "err", err.Error())
+			s.disconnect(plr, "Invalid shared secret")
+			return
 		}
-		err = plr.SendPacket(spawnPositionPkt)
+
+		err = plr.EnableEncryption(sharedSecret)
 		if err != nil {
-			slog.Error("error sending spawn position")
+			slog.Error("error enabling encryption", "err", err.Error())
+			s.disconnect(plr, "Invalid shared secret")
 			return
 		}
-	default:
-		slog.Error("login id not implemented", "id", pkt.ID())
-	}
 
-}
+		ctx, cancel := context.WithTimeout(context.Background(), sessionVerifyTimeout)
+		defer cancel()
 
-func (s *Server) handlePlayState(plr *player.Player, pkt *packet.Packet) {
-	switch pkt.ID() {
-	case packet.IDClientKeepAlive:
-		rndId, _ := pkt.Buffer().ReadInt()
-		slog.Info("Client sent KeepAlive packet!", "id", rndId)
-	case packet.IDClientPlayer:
-		// This packet is used to indicate whether the player is on ground (walking/swimming), or airborne (jumping/falling).
-		plr.Position.OnGround, _ = pkt.Buffer().ReadBool()
-	case packet.IDClientClientSettings: // Sent when the player connects, or when settings are changed.
-		clientSettings, err := protocol.ReceiveClientSettings(pkt)
+		serverHash := auth.ServerHash(serverID, sharedSecret, s.crypto.PublicKeyBytes())
+		profile, err := s.sessionVerifier.Verify(ctx, plr.Name, serverHash)
 		if err != nil {
-			slog.Error("error receiving client settings packet", "err", err.Error())
+			slog.Error("error verifying session", "name", plr.Name, "err", err.Error())
+			s.disconnect(plr, "Failed to verify username!")
 			return
 		}
-		_ = clientSettings
-		// I don't know exactly how to deal with it yet <-<
-	case packet.IDClientPluginMessage: // Plugin Message
-		pluginMessage, err := protocol.ReceivePluginMessage(pkt)
+
+		plr.UUID = profile.ID
+		plr.Name = profile.Name
+		plr.Properties = profile.Properties
+
+		s.finishLogin(plr)
+	}
+}
+
+// finishLogin lets the player in once it has been identified.
+func (s *Server) finishLogin(plr *player.Player) {
+	err := s.sendPacket(plr, &protocol.LoginSuccessPacket{
+		UUID:     plr.UUID.String(),
+		Username: plr.Name,
+	})
+	if err != nil {
+		slog.Error("error sending login success packet", "err", err.Error())
+		return
+	}
+
+	// change the state to game mode
+	plr.State.SetState(fsm.FSMStatePlay)
+	s.playersMu.Lock()
+	plr.IsLoggedIn = true
+	s.playersMu.Unlock()
+
+	err = s.sendPacket(plr, &protocol.JoinGamePacket{
+		EntityID:   0,
+		GameMode:   1,
+		Dimension:  0,
+		Difficulty: 1,
+		MaxPlayers: 2,
+		LevelType:  "default",
+	})
+	if err != nil {
+		slog.Error("Error sending join game packet", "err", err.Error())
+		return
+	}
+
+	// send the spawn position
+	err = s.sendPacket(plr, &protocol.SpawnPositionPacket{
+		Location: raknet.Position{X: int32(spawn.X), Y: int32(spawn.FeetY), Z: int32(spawn.Z)},
+	})
+	if err != nil {
+		slog.Error("error sending spawn position", "err", err.Error())
+		return
+	}
+
+	err = s.sendSpawnArea(plr)
+	if err != nil {
+		slog.Error("error sending spawn area", "err", err.Error())
+		return
+	}
+}
+
+// disconnect kicks a player, whether it is still logging in or already
+// playing.
+func (s *Server) disconnect(plr *player.Player, reason string) {
+	slog.Info("Disconnecting player", "name", plr.Name, "reason", reason)
+
+	var disconnectPkt packet.Codec
+	var err error
+	if plr.State.State() == fsm.FSMStatePlay {
+		disconnectPkt, err = protocol.NewDisconnectPacket(chat.Text(reason))
+	} else {
+		disconnectPkt, err = protocol.NewLoginDisconnectPacket(chat.Text(reason))
+	}
+	if err != nil {
+		slog.Error("error creating disconnect packet", "err", err.Error())
+	} else {
+		err = s.sendPacket(plr, disconnectPkt)
 		if err != nil {
-			slog.Error("error receiving plugin message packet", "err", err.Error())
-			return
+			slog.Error("error sending disconnect packet", "err", err.Error())
 		}
-		if pluginMessage.Channel == "MC|Brand" {
-			pluginMessagePkt, err := protocol.CreatePluginMessagePacket(pluginMessage)
+	}
+
+	plr.Close()
+}
+
+func (s *Server) handlePlayState(plr *player.Player, pkt packet.Codec) {
+	switch pkt := pkt.(type) {
+	case *protocol.KeepAlivePacket:
+		slog.Info("Client sent KeepAlive packet!", "id", pkt.ID)
+	case *protocol.ClientChatMessagePacket:
+		s.handleChat(plr, pkt)
+	case *protocol.ClientTabCompletePacket:
+		s.handleTabComplete(plr, pkt)
+	case *protocol.PlayerPacket:
+		plr.Position.OnGround = pkt.OnGround
+	case *protocol.ClientSettings: // Sent when the player connects, or when settings are changed.
+		if int(pkt.ViewDistance) != plr.ViewDistance {
+			plr.ViewDistance = int(pkt.ViewDistance)
+			err := s.sendChunks(plr)
 			if err != nil {
-				slog.Error("error creating plugin message packet", "err", err.Error())
-				return
+				slog.Error("error sending chunks", "err", err.Error())
 			}
-
-			err = plr.SendPacket(pluginMessagePkt)
+		}
+	case *protocol.PluginMessage:
+		if pkt.Channel == "MC|Brand" {
+			err := s.sendPacket(plr, pkt)
 			if err != nil {
 				slog.Error("error sending plugin message packet", "err", err.Error())
 				return
 			}
 		}
-	case packet.IDClientPlayerPosition:
-		playerPosition, err := protocol.ReceivePlayerPosition(pkt)
-		if err != nil {
-			slog.Error("error receiving player position", "err", err.Error())
-			return
-		}
-
-		_ = playerPosition
+	case *protocol.PlayerPositionPacket:
+		s.move(plr, pkt.Position())
+	case *protocol.PlayerPositionAndLookPacket:
+		s.move(plr, pkt.Position())
 	default:
-		slog.Error("Play State not implemented yet", "id", pkt.ID())
+		slog.Error("Play State not implemented yet", "type", fmt.Sprintf("%T", pkt))
 	}
 }
 
+// loggedInPlayers returns the players that are playing, safe to use after
+// they leave.
+func (s *Server) loggedInPlayers() []*player.Player {
+	s.playersMu.Lock()
+	defer s.playersMu.Unlock()
+
+	players := make([]*player.Player, 0, len(s.players))
+	for _, plr := range s.players {
+		if plr.IsLoggedIn {
+			players = append(players, plr)
+		}
+	}
+	return players
+}
+
 func (s *Server) closeConn(plr *player.Player) error {
 	addr := plr.Conn.RemoteAddr().String()
-	_, exists := s.players[addr]
-	if exists {
-		delete(s.players, addr)
-	}
+	s.playersMu.Lock()
+	delete(s.players, addr)
+	plr.IsLoggedIn = false
+	s.playersMu.Unlock()
 	slog.Info("Connection Closed", "name", plr.Name, "addr", addr)
-	return plr.Conn.Close()
+	return plr.Close()
 }
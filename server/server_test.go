@@ -0,0 +1,294 @@
+package server_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/auth"
+	"github.com/jnaraujo/mcprotocol/chat"
+	"github.com/jnaraujo/mcprotocol/client"
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/internal/mctest"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/server"
+	"github.com/jnaraujo/mcprotocol/world"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyPing(t *testing.T) {
+	addr := mctest.StartServer(t)
+
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	// what a 1.6 client sends: FE 01, then an MC|PingHost plugin message
+	request := []byte{0xFE, 0x01, 0xFA}
+	request = binary.BigEndian.AppendUint16(request, 11)
+	for _, c := range "MC|PingHost" {
+		request = binary.BigEndian.AppendUint16(request, uint16(c))
+	}
+	host := "localhost"
+	request = binary.BigEndian.AppendUint16(request, uint16(7+2*len(host)))
+	request = append(request, 78)
+	request = binary.BigEndian.AppendUint16(request, uint16(len(host)))
+	for _, c := range host {
+		request = binary.BigEndian.AppendUint16(request, uint16(c))
+	}
+	request = binary.BigEndian.AppendUint32(request, 25565)
+	// the server answers on the first byte, so the rest arrives after the
+	// answer, like it can from a real client
+	_, err = conn.Write(request[:1])
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = conn.Write(request[1:])
+	assert.Nil(t, err)
+	conn.(*net.TCPConn).CloseWrite()
+	time.Sleep(50 * time.Millisecond)
+
+	// the connection ends cleanly, not with a reset that would lose the
+	// answer
+	conn.SetReadDeadline(time.Now().Add(mctest.Timeout))
+	response, err := io.ReadAll(conn)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(0xFF), response[0])
+	assert.Equal(t, 3+2*int(binary.BigEndian.Uint16(response[1:])), len(response))
+}
+
+func TestLoginInvalidSharedSecret(t *testing.T) {
+	addr := mctest.StartServer(t, server.WithOnlineMode(true), server.WithSessionVerifier(mctest.SessionVerifier{}))
+
+	// the client can't be made to send a bad secret, so the login is done
+	// by hand
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mctest.Timeout))
+	w := packet.NewWriter(conn, packet.DefaultWriterQueueSize)
+	defer w.Close()
+	r := packet.NewReader(conn)
+
+	send := func(state fsm.FSMState, codec packet.Codec) {
+		pkt, err := protocol.Registry.Packet(protocol.Version1_7_10, state, packet.Serverbound, codec)
+		assert.Nil(t, err)
+		assert.Nil(t, w.WritePacket(pkt))
+		pkt.Release()
+	}
+	receive := func() packet.Codec {
+		pkt, err := r.ReadPacket()
+		assert.Nil(t, err)
+		codec, err := protocol.Registry.Lookup(protocol.Version1_7_10, fsm.FSMStateLogin, packet.Clientbound, pkt)
+		assert.Nil(t, err)
+		return codec
+	}
+
+	send(fsm.FSMStateHandshake, &protocol.HandshakePacket{
+		ProtocolVersion: protocol.Version1_7_10,
+		Addr:            "localhost",
+		Port:            25565,
+		NextState:       protocol.HandshakeNextStateLogin,
+	})
+	send(fsm.FSMStateLogin, &protocol.LoginStartPacket{Name: "jeb_"})
+	request, ok := receive().(*protocol.EncryptionRequestPacket)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	// AES takes a 32 byte key, but the secret is also the IV
+	encryptedSecret, err := auth.EncryptWithPublicKey(request.PublicKey, make([]byte, 32))
+	assert.Nil(t, err)
+	encryptedToken, err := auth.EncryptWithPublicKey(request.PublicKey, request.VerifyToken)
+	assert.Nil(t, err)
+	send(fsm.FSMStateLogin, &protocol.EncryptionResponsePacket{
+		SharedSecret: encryptedSecret,
+		VerifyToken:  encryptedToken,
+	})
+
+	disconnect, ok := receive().(*protocol.LoginDisconnectPacket)
+	if !assert.True(t, ok) {
+		return
+	}
+	reason, err := disconnect.Message()
+	assert.Nil(t, err)
+	assert.Equal(t, "Invalid shared secret", reason.PlainText())
+
+	// the server is still up
+	mctest.Login(t, addr, "Notch").Close()
+}
+
+func TestSpawnArea(t *testing.T) {
+	c := mctest.Login(t, mctest.StartServer(t), "Steve")
+	defer c.Close()
+
+	// the terrain comes before the player is moved into it
+	loaded, unloaded := receiveChunks(t, c)
+	assert.Len(t, loaded, 21*21)
+	assert.Empty(t, unloaded)
+	assert.True(t, loaded[world.ChunkPos{X: -10, Z: 10}])
+}
+
+func TestChunksFollowPlayer(t *testing.T) {
+	c := mctest.Login(t, mctest.StartServer(t, server.WithViewDistance(3)), "Steve")
+	defer c.Close()
+	loaded, _ := receiveChunks(t, c)
+	assert.Len(t, loaded, 7*7)
+
+	// asking for less unloads the farthest columns
+	assert.Nil(t, c.Send(&protocol.ClientSettings{Locale: "en_US", ViewDistance: 2}))
+	assert.Nil(t, c.Send(&protocol.ClientTabCompletePacket{Text: "/"}))
+	loaded, unloaded := receiveChunks(t, c)
+	assert.Empty(t, loaded)
+	assert.Len(t, unloaded, 7*7-5*5)
+
+	// moving a column east unloads the west edge and loads the east one
+	assert.Nil(t, c.Send(&protocol.PlayerPositionPacket{X: 16.5, FeetY: 4, HeadY: 5.62, Z: 0.5}))
+	assert.Nil(t, c.Send(&protocol.ClientTabCompletePacket{Text: "/"}))
+	loaded, unloaded = receiveChunks(t, c)
+	assert.Len(t, loaded, 5)
+	assert.Len(t, unloaded, 5)
+	for z := int32(-2); z <= 2; z++ {
+		assert.True(t, unloaded[world.ChunkPos{X: -2, Z: z}])
+		assert.True(t, loaded[world.ChunkPos{X: 3, Z: z}])
+	}
+
+	// moving while turning does the same
+	assert.Nil(t, c.Send(&protocol.PlayerPositionAndLookPacket{X: 0.5, FeetY: 4, HeadY: 5.62, Z: 0.5, Yaw: 90}))
+	assert.Nil(t, c.Send(&protocol.ClientTabCompletePacket{Text: "/"}))
+	loaded, unloaded = receiveChunks(t, c)
+	assert.Len(t, loaded, 5)
+	assert.Len(t, unloaded, 5)
+	for z := int32(-2); z <= 2; z++ {
+		assert.True(t, unloaded[world.ChunkPos{X: 3, Z: z}])
+		assert.True(t, loaded[world.ChunkPos{X: -2, Z: z}])
+	}
+}
+
+// receiveChunks collects the columns the server loads and unloads until the
+// player is moved or a tab completion, sent by the test after the packets
+// that change the columns, is answered.
+func receiveChunks(t *testing.T, c *client.Client) (loaded, unloaded map[world.ChunkPos]bool) {
+	stop := mctest.Watch(c)
+	defer stop()
+
+	loaded = make(map[world.ChunkPos]bool)
+	unloaded = make(map[world.ChunkPos]bool)
+	for {
+		codec, err := c.Receive()
+		if errors.Is(err, packet.ErrUnknownPacket) {
+			continue
+		}
+		if !assert.Nil(t, err) {
+			return loaded, unloaded
+		}
+
+		switch pkt := codec.(type) {
+		case *protocol.MapChunkBulkPacket:
+			columns, err := pkt.Uncompressed()
+			assert.Nil(t, err)
+			assert.Len(t, columns, len(pkt.Columns))
+			for _, col := range pkt.Columns {
+				assert.Equal(t, uint16(1), col.PrimaryBitMask)
+				loaded[world.ChunkPos{X: col.X, Z: col.Z}] = true
+			}
+		case *protocol.ChunkDataPacket:
+			assert.Zero(t, pkt.PrimaryBitMask)
+			unloaded[world.ChunkPos{X: pkt.X, Z: pkt.Z}] = true
+		case *protocol.ServerPlayerPositionAndLookPacket:
+			assert.Equal(t, 4.0, pkt.FeetY)
+			return loaded, unloaded
+		case *protocol.ServerTabCompletePacket:
+			return loaded, unloaded
+		}
+	}
+}
+
+func TestChat(t *testing.T) {
+	addr := mctest.StartServer(t)
+	alice := mctest.Login(t, addr, "Alice")
+	defer alice.Close()
+	bob := mctest.Login(t, addr, "Bob")
+	defer bob.Close()
+
+	err := alice.Send(&protocol.ClientChatMessagePacket{Message: " hello "})
+	assert.Nil(t, err)
+
+	for _, c := range []*client.Client{alice, bob} {
+		msg, err := mctest.Receive[*protocol.ServerChatMessagePacket](t, c).Message()
+		assert.Nil(t, err)
+		assert.Equal(t, server.FormatChat("Alice", "hello"), msg)
+	}
+
+	err = bob.Send(&protocol.ClientChatMessagePacket{Message: "§cred"})
+	assert.Nil(t, err)
+	disconnect := mctest.Receive[*protocol.DisconnectPacket](t, bob)
+	reason, err := disconnect.Message()
+	assert.Nil(t, err)
+	assert.Equal(t, "Illegal characters in chat", reason.PlainText())
+}
+
+func TestChatTooLong(t *testing.T) {
+	c := mctest.Login(t, mctest.StartServer(t), "Notch")
+	defer c.Close()
+
+	err := c.Send(&protocol.ClientChatMessagePacket{Message: strings.Repeat("a", protocol.MaxChatLength+1)})
+	assert.Nil(t, err)
+	disconnect := mctest.Receive[*protocol.DisconnectPacket](t, c)
+	reason, err := disconnect.Message()
+	assert.Nil(t, err)
+	assert.Equal(t, "Chat message too long", reason.PlainText())
+}
+
+func TestChatHook(t *testing.T) {
+	addr := mctest.StartServer(t, server.WithChatHook(func(plr *player.Player, message string) (chat.Component, bool) {
+		if message == "secret" {
+			return chat.Component{}, false
+		}
+		return chat.Text(plr.Name + ": " + strings.ToUpper(message)), true
+	}))
+	c := mctest.Login(t, addr, "Alex")
+	defer c.Close()
+
+	c.Send(&protocol.ClientChatMessagePacket{Message: "secret"})
+	c.Send(&protocol.ClientChatMessagePacket{Message: "hi"})
+
+	// the cancelled message never arrives
+	msg, err := mctest.Receive[*protocol.ServerChatMessagePacket](t, c).Message()
+	assert.Nil(t, err)
+	assert.Equal(t, "Alex: HI", msg.PlainText())
+}
+
+func TestCommands(t *testing.T) {
+	c := mctest.Login(t, mctest.StartServer(t), "Alice")
+	defer c.Close()
+
+	c.Send(&protocol.ClientChatMessagePacket{Message: "/nope"})
+	msg, err := mctest.Receive[*protocol.ServerChatMessagePacket](t, c).Message()
+	assert.Nil(t, err)
+	assert.Equal(t, "commands.generic.notFound", msg.Translate)
+	assert.Equal(t, chat.Red, msg.Color)
+
+	c.Send(&protocol.ClientChatMessagePacket{Message: "/help"})
+	msg, err = mctest.Receive[*protocol.ServerChatMessagePacket](t, c).Message()
+	assert.Nil(t, err)
+	assert.Equal(t, "/help - Shows the commands you can use", msg.PlainText())
+
+	for _, test := range []struct {
+		text    string
+		matches []string
+	}{
+		{"/he", []string{"/help"}},
+		{"hi a", []string{"Alice"}},
+		{"/help ", []string{}},
+	} {
+		c.Send(&protocol.ClientTabCompletePacket{Text: test.text})
+		matches := mctest.Receive[*protocol.ServerTabCompletePacket](t, c).Matches
+		assert.Equal(t, test.matches, matches, test.text)
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/world"
+)
+
+// defaultViewDistance is how many columns around them players are sent at
+// most, in every direction, like the view-distance of vanilla servers.
+const defaultViewDistance = 10
+
+const (
+	blockBedrock = 7
+	blockDirt    = 3
+	blockGrass   = 2
+	biomePlains  = 1
+)
+
+// flatHeight is the height of the ground of the flat world.
+const flatHeight = 4
+
+// spawn is where players appear, on top of the ground in the middle of the
+// first block.
+var spawn = player.Position{X: 0.5, FeetY: flatHeight, Z: 0.5, OnGround: true}
+
+// flatColumn generates the column at x, z of a flat world: bedrock, two
+// layers of dirt and grass on top.
+func flatColumn(x, z int32) *world.Column {
+	col := world.NewColumn(x, z)
+	for bx := 0; bx < world.SectionSize; bx++ {
+		for bz := 0; bz < world.SectionSize; bz++ {
+			col.SetBlock(bx, 0, bz, blockBedrock)
+			col.SetBlock(bx, 1, bz, blockDirt)
+			col.SetBlock(bx, 2, bz, blockDirt)
+			col.SetBlock(bx, 3, bz, blockGrass)
+			col.SetBiome(bx, bz, biomePlains)
+		}
+	}
+	return col
+}
+
+// sendSpawnArea sends the columns around the spawn, then moves the player
+// to it, which lets the client out of the "Downloading terrain" screen.
+func (s *Server) sendSpawnArea(plr *player.Player) error {
+	plr.Position = spawn
+	err := s.sendChunks(plr)
+	if err != nil {
+		return err
+	}
+	return s.sendPacket(plr, protocol.NewServerPlayerPositionAndLookPacket(spawn, 0, 0))
+}
+
+// move moves plr to pos, sending it the columns it can now see when it
+// entered another one.
+func (s *Server) move(plr *player.Player, pos player.Position) {
+	from := world.ChunkPosAt(plr.Position.X, plr.Position.Z)
+	plr.Position = pos
+	if world.ChunkPosAt(pos.X, pos.Z) == from {
+		return
+	}
+	err := s.sendChunks(plr)
+	if err != nil {
+		slog.Error("error sending chunks", "err", err.Error())
+	}
+}
+
+// sendChunks brings the columns the player has in line with where it is:
+// the ones it moved away from are unloaded, then the ones it is missing in
+// its view distance are sent in Map Chunk Bulks, nearest first.
+func (s *Server) sendChunks(plr *player.Player) error {
+	// only the 1.7.10 chunk format is implemented, 1.8 clients get an
+	// empty world
+	if s.protocolVersion(plr) != protocol.Version1_7_10 {
+		slog.Debug("not sending terrain", "protocol", plr.ProtocolVersion)
+		return nil
+	}
+
+	distance := int32(s.viewDistance)
+	if plr.ViewDistance > 0 {
+		distance = min(distance, int32(plr.ViewDistance))
+	}
+	center := world.ChunkPosAt(plr.Position.X, plr.Position.Z)
+	inView := func(pos world.ChunkPos) bool {
+		return abs(pos.X-center.X) <= distance && abs(pos.Z-center.Z) <= distance
+	}
+
+	if plr.LoadedChunks == nil {
+		plr.LoadedChunks = make(map[world.ChunkPos]bool)
+	}
+	// every column is unloaded with a packet of its own, which are sent
+	// together so that lowering the view distance or jumping far away
+	// can't fill the queue of the connection
+	var unloads []packet.Codec
+	var unloaded []world.ChunkPos
+	for pos := range plr.LoadedChunks {
+		if inView(pos) {
+			continue
+		}
+		pkt, err := protocol.NewChunkUnloadPacket(pos.X, pos.Z)
+		if err != nil {
+			return err
+		}
+		unloads = append(unloads, pkt)
+		unloaded = append(unloaded, pos)
+	}
+	if len(unloads) > 0 {
+		err := s.sendPackets(plr, unloads)
+		if err != nil {
+			return err
+		}
+		for _, pos := range unloaded {
+			delete(plr.LoadedChunks, pos)
+		}
+	}
+
+	var missing []world.ChunkPos
+	for x := center.X - distance; x <= center.X+distance; x++ {
+		for z := center.Z - distance; z <= center.Z+distance; z++ {
+			pos := world.ChunkPos{X: x, Z: z}
+			if !plr.LoadedChunks[pos] {
+				missing = append(missing, pos)
+			}
+		}
+	}
+	slices.SortFunc(missing, func(a, b world.ChunkPos) int {
+		return cmp.Compare(distanceSq(a, center), distanceSq(b, center))
+	})
+
+	cols := make([]*world.Column, len(missing))
+	for i, pos := range missing {
+		cols[i] = flatColumn(pos.X, pos.Z)
+	}
+	pkts, err := protocol.NewMapChunkBulkPackets(cols, true)
+	if err != nil {
+		return err
+	}
+	for _, pkt := range pkts {
+		err = s.sendPacket(plr, pkt)
+		if err != nil {
+			return err
+		}
+		for _, col := range pkt.Columns {
+			plr.LoadedChunks[world.ChunkPos{X: col.X, Z: col.Z}] = true
+		}
+	}
+	return nil
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func distanceSq(a, b world.ChunkPos) int32 {
+	dx, dz := a.X-b.X, a.Z-b.Z
+	return dx*dx + dz*dz
+}
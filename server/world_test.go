@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jnaraujo/mcprotocol/fsm"
+	"github.com/jnaraujo/mcprotocol/packet"
+	"github.com/jnaraujo/mcprotocol/player"
+	"github.com/jnaraujo/mcprotocol/protocol"
+	"github.com/jnaraujo/mcprotocol/world"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPipePlayer returns a 1.7.10 player in the play state, whose packets are
+// only written as fast as they are read from the returned connection.
+func newPipePlayer(t *testing.T) (*player.Player, net.Conn) {
+	serverConn, clientConn := net.Pipe()
+	plr := player.NewPlayer(serverConn)
+	plr.ProtocolVersion = protocol.Version1_7_10
+	plr.State.SetState(fsm.FSMStatePlay)
+	t.Cleanup(func() {
+		clientConn.Close()
+		plr.Close()
+	})
+	return plr, clientConn
+}
+
+// readChunks reads packets from conn until as many different columns as
+// asked for were loaded and unloaded.
+func readChunks(t *testing.T, conn net.Conn, loads, unloads int) (loaded, unloaded map[world.ChunkPos]bool) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := packet.NewReader(conn)
+
+	loaded = make(map[world.ChunkPos]bool)
+	unloaded = make(map[world.ChunkPos]bool)
+	for len(loaded) < loads || len(unloaded) < unloads {
+		pkt, err := r.ReadPacket()
+		if !assert.Nil(t, err) {
+			return loaded, unloaded
+		}
+		codec, err := protocol.Registry.Lookup(protocol.Version1_7_10, fsm.FSMStatePlay, packet.Clientbound, pkt)
+		if !assert.Nil(t, err) {
+			return loaded, unloaded
+		}
+
+		switch pkt := codec.(type) {
+		case *protocol.MapChunkBulkPacket:
+			for _, col := range pkt.Columns {
+				loaded[world.ChunkPos{X: col.X, Z: col.Z}] = true
+			}
+		case *protocol.ChunkDataPacket:
+			unloaded[world.ChunkPos{X: pkt.X, Z: pkt.Z}] = true
+		}
+	}
+	return loaded, unloaded
+}
+
+func TestSendChunksUnloadsWhileBlocked(t *testing.T) {
+	s := NewServer("")
+	plr, conn := newPipePlayer(t)
+	assert.Nil(t, s.sendSpawnArea(plr))
+
+	// nothing is read until the end, so the writer is stuck on the spawn
+	// area while far more columns are unloaded than its queue holds
+	plr.ViewDistance = 2
+	assert.Nil(t, s.sendChunks(plr))
+	assert.Equal(t, 5*5, len(plr.LoadedChunks))
+
+	// and all of them again after jumping far away
+	plr.ViewDistance = 0
+	s.move(plr, player.Position{X: 1000.5, FeetY: flatHeight, Z: 0.5})
+	assert.Equal(t, 21*21, len(plr.LoadedChunks))
+
+	// the two areas don't overlap
+	loaded, unloaded := readChunks(t, conn, 2*21*21, 21*21)
+	assert.True(t, loaded[world.ChunkPos{X: -10, Z: -10}])
+	assert.True(t, loaded[world.ChunkPos{X: 62 + 10, Z: 10}])
+	assert.True(t, unloaded[world.ChunkPos{X: -10, Z: -10}])
+	assert.True(t, unloaded[world.ChunkPos{X: 2, Z: 2}])
+}
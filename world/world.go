@@ -0,0 +1,327 @@
+// Package world stores the blocks of the world the way 1.7.10 sends them to
+// clients.
+//
+// A Column is a 16×256×16 piece of the world, made of 16 sections stacked on
+// top of each other. Each Section is 16×16×16 blocks and keeps, for every
+// block, its id, its metadata and how much light reaches it. Sections with
+// no blocks in them are not stored at all.
+package world
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// SectionSize is the width, length and height of a section.
+	SectionSize = 16
+	// SectionsPerColumn is how many sections are stacked in a column.
+	SectionsPerColumn = 16
+	// Height is the height of the world.
+	Height = SectionSize * SectionsPerColumn
+
+	blocksPerSection = SectionSize * SectionSize * SectionSize
+	biomesPerColumn  = SectionSize * SectionSize
+
+	// MaxLight is the brightest light level.
+	MaxLight = 15
+	// MaxBlockID is the biggest block id a section can hold, using its add
+	// array for the upper 4 bits.
+	MaxBlockID = 1<<12 - 1
+)
+
+// Air is the id of the empty block.
+const Air = 0
+
+// NibbleArray holds one 4 bit value per block of a section, two per byte.
+// The even block of each pair is in the low nibble.
+type NibbleArray [blocksPerSection / 2]byte
+
+func (a *NibbleArray) Get(index int) byte {
+	if index&1 == 0 {
+		return a[index>>1] & 0x0F
+	}
+	return a[index>>1] >> 4
+}
+
+func (a *NibbleArray) Set(index int, value byte) {
+	value &= 0x0F
+	if index&1 == 0 {
+		a[index>>1] = a[index>>1]&0xF0 | value
+	} else {
+		a[index>>1] = a[index>>1]&0x0F | value<<4
+	}
+}
+
+// Section is 16×16×16 blocks of a column. Blocks are indexed by y<<8|z<<4|x,
+// with coordinates relative to the section.
+type Section struct {
+	// Blocks holds the lower 8 bits of every block id
+	Blocks     [blocksPerSection]byte
+	Metadata   NibbleArray
+	BlockLight NibbleArray
+	SkyLight   NibbleArray
+	// Add holds the upper 4 bits of the block ids. It is nil until a block
+	// id above 255 is set.
+	Add *NibbleArray
+}
+
+// NewSection returns an empty section lit by the sky.
+func NewSection() *Section {
+	s := &Section{}
+	for i := range s.SkyLight {
+		s.SkyLight[i] = MaxLight<<4 | MaxLight
+	}
+	return s
+}
+
+func sectionIndex(x, y, z int) int {
+	return (y&15)<<8 | (z&15)<<4 | x&15
+}
+
+func (s *Section) Block(x, y, z int) int {
+	i := sectionIndex(x, y, z)
+	id := int(s.Blocks[i])
+	if s.Add != nil {
+		id |= int(s.Add.Get(i)) << 8
+	}
+	return id
+}
+
+// SetBlock sets the id of a block, keeping only its lower 12 bits.
+func (s *Section) SetBlock(x, y, z, id int) {
+	i := sectionIndex(x, y, z)
+	s.Blocks[i] = byte(id)
+	add := byte(id>>8) & 0x0F
+	if s.Add == nil {
+		if add == 0 {
+			return
+		}
+		s.Add = &NibbleArray{}
+	}
+	s.Add.Set(i, add)
+}
+
+// Empty reports whether every block of the section is air.
+func (s *Section) Empty() bool {
+	for _, b := range s.Blocks {
+		if b != 0 {
+			return false
+		}
+	}
+	if s.Add != nil {
+		for _, b := range s.Add {
+			if b != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Column is a 16×256×16 piece of the world. X and Z are its coordinates in
+// columns, which is the block coordinates divided by 16.
+//
+// The methods taking block coordinates accept them either relative to the
+// column or absolute, since only their lower 4 bits are used. Blocks above
+// or below the world are air.
+type Column struct {
+	X, Z     int32
+	Sections [SectionsPerColumn]*Section
+	// Biomes holds the biome id of every x, z pair, indexed by z<<4|x
+	Biomes [biomesPerColumn]byte
+}
+
+// NewColumn returns a column with nothing in it.
+func NewColumn(x, z int32) *Column {
+	return &Column{X: x, Z: z}
+}
+
+// section returns the section holding height y, or nil when there is none.
+func (c *Column) section(y int) *Section {
+	if y < 0 || y >= Height {
+		return nil
+	}
+	return c.Sections[y/SectionSize]
+}
+
+// sectionForWrite returns the section holding height y, creating it when
+// there is none. It returns nil when y is outside the world.
+func (c *Column) sectionForWrite(y int) *Section {
+	if y < 0 || y >= Height {
+		return nil
+	}
+	s := c.Sections[y/SectionSize]
+	if s == nil {
+		s = NewSection()
+		c.Sections[y/SectionSize] = s
+	}
+	return s
+}
+
+func (c *Column) Block(x, y, z int) int {
+	s := c.section(y)
+	if s == nil {
+		return Air
+	}
+	return s.Block(x, y, z)
+}
+
+func (c *Column) SetBlock(x, y, z, id int) {
+	if id == Air && c.section(y) == nil {
+		return
+	}
+	if s := c.sectionForWrite(y); s != nil {
+		s.SetBlock(x, y, z, id)
+	}
+}
+
+func (c *Column) Metadata(x, y, z int) byte {
+	s := c.section(y)
+	if s == nil {
+		return 0
+	}
+	return s.Metadata.Get(sectionIndex(x, y, z))
+}
+
+func (c *Column) SetMetadata(x, y, z int, metadata byte) {
+	if metadata == 0 && c.section(y) == nil {
+		return
+	}
+	if s := c.sectionForWrite(y); s != nil {
+		s.Metadata.Set(sectionIndex(x, y, z), metadata)
+	}
+}
+
+func (c *Column) BlockLight(x, y, z int) byte {
+	s := c.section(y)
+	if s == nil {
+		return 0
+	}
+	return s.BlockLight.Get(sectionIndex(x, y, z))
+}
+
+func (c *Column) SetBlockLight(x, y, z int, light byte) {
+	if light == 0 && c.section(y) == nil {
+		return
+	}
+	if s := c.sectionForWrite(y); s != nil {
+		s.BlockLight.Set(sectionIndex(x, y, z), light)
+	}
+}
+
+// SkyLight returns how much sky light reaches a block. Where there is no
+// section, the sky is in full view.
+func (c *Column) SkyLight(x, y, z int) byte {
+	s := c.section(y)
+	if s == nil {
+		return MaxLight
+	}
+	return s.SkyLight.Get(sectionIndex(x, y, z))
+}
+
+func (c *Column) SetSkyLight(x, y, z int, light byte) {
+	if light == MaxLight && c.section(y) == nil {
+		return
+	}
+	if s := c.sectionForWrite(y); s != nil {
+		s.SkyLight.Set(sectionIndex(x, y, z), light)
+	}
+}
+
+func (c *Column) Biome(x, z int) byte {
+	return c.Biomes[(z&15)<<4|x&15]
+}
+
+func (c *Column) SetBiome(x, z int, biome byte) {
+	c.Biomes[(z&15)<<4|x&15] = biome
+}
+
+// Bitmasks returns which sections are sent to clients, one bit per section
+// from the bottom up, and which of them have an add array.
+func (c *Column) Bitmasks() (primary, add uint16) {
+	for i, s := range c.Sections {
+		if s == nil || s.Empty() {
+			continue
+		}
+		primary |= 1 << i
+		if s.Add != nil {
+			add |= 1 << i
+		}
+	}
+	return primary, add
+}
+
+// Data returns the column the way the Chunk Data and Map Chunk Bulk packets
+// carry it, before compression, along with its bitmasks. Every array is
+// written for all the sent sections before the next one: block ids,
+// metadata, block light, sky light when skyLight is set (it is not in the
+// nether and the end) and the add arrays. The biomes follow when groundUp
+// is set, meaning the whole column is sent rather than some of its
+// sections.
+func (c *Column) Data(skyLight, groundUp bool) (data []byte, primary, add uint16) {
+	primary, add = c.Bitmasks()
+
+	var sections []*Section
+	for i, s := range c.Sections {
+		if primary&(1<<i) != 0 {
+			sections = append(sections, s)
+		}
+	}
+
+	data = make([]byte, 0, DataSize(primary, add, skyLight, groundUp))
+	for _, s := range sections {
+		data = append(data, s.Blocks[:]...)
+	}
+	for _, s := range sections {
+		data = append(data, s.Metadata[:]...)
+	}
+	for _, s := range sections {
+		data = append(data, s.BlockLight[:]...)
+	}
+	if skyLight {
+		for _, s := range sections {
+			data = append(data, s.SkyLight[:]...)
+		}
+	}
+	for _, s := range sections {
+		if s.Add != nil {
+			data = append(data, s.Add[:]...)
+		}
+	}
+	if groundUp {
+		data = append(data, c.Biomes[:]...)
+	}
+	return data, primary, add
+}
+
+// DataSize returns the length of the data of a column with the given
+// bitmasks, as returned by Column.Data.
+func DataSize(primary, add uint16, skyLight, groundUp bool) int {
+	nibbles := 2
+	if skyLight {
+		nibbles++
+	}
+	sections := bits.OnesCount16(primary)
+	size := sections*(blocksPerSection+nibbles*len(NibbleArray{})) + bits.OnesCount16(add)*len(NibbleArray{})
+	if groundUp {
+		size += biomesPerColumn
+	}
+	return size
+}
+
+// ChunkPos is the position of a column, in columns.
+type ChunkPos struct {
+	X, Z int32
+}
+
+// ChunkPosAt returns the position of the column holding the block coordinates
+// x, z.
+func ChunkPosAt(x, z float64) ChunkPos {
+	return ChunkPos{X: int32(math.Floor(x)) >> 4, Z: int32(math.Floor(z)) >> 4}
+}
+
+// Pos returns the position of the column.
+func (c *Column) Pos() ChunkPos {
+	return ChunkPos{X: c.X, Z: c.Z}
+}
@@ -0,0 +1,105 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNibbleArray(t *testing.T) {
+	var a NibbleArray
+	a.Set(0, 0x3)
+	a.Set(1, 0xA)
+	a.Set(3, 0x1F)
+	assert.Equal(t, byte(0xA3), a[0])
+	assert.Equal(t, byte(0xF0), a[1])
+	assert.Equal(t, byte(0x3), a.Get(0))
+	assert.Equal(t, byte(0xA), a.Get(1))
+	assert.Equal(t, byte(0), a.Get(2))
+	assert.Equal(t, byte(0xF), a.Get(3))
+}
+
+func TestColumnBlocks(t *testing.T) {
+	col := NewColumn(2, -1)
+	assert.Equal(t, Air, col.Block(0, 0, 0))
+	assert.Equal(t, byte(MaxLight), col.SkyLight(0, 100, 0))
+
+	// setting air where there is nothing doesn't create a section
+	col.SetBlock(0, 100, 0, Air)
+	assert.Nil(t, col.Sections[6])
+
+	col.SetBlock(1, 17, 2, 1)
+	col.SetMetadata(1, 17, 2, 3)
+	col.SetBlockLight(1, 17, 2, 14)
+	col.SetSkyLight(1, 17, 2, 4)
+	assert.Equal(t, 1, col.Block(1, 17, 2))
+	assert.Equal(t, byte(3), col.Metadata(1, 17, 2))
+	assert.Equal(t, byte(14), col.BlockLight(1, 17, 2))
+	assert.Equal(t, byte(4), col.SkyLight(1, 17, 2))
+	assert.Equal(t, byte(MaxLight), col.SkyLight(0, 17, 0))
+	assert.Equal(t, byte(1), col.Sections[1].Blocks[1<<8|2<<4|1])
+
+	// absolute coordinates work as well
+	assert.Equal(t, 1, col.Block(33, 17, -14))
+
+	// ids above 255 go in the add array
+	assert.Nil(t, col.Sections[1].Add)
+	col.SetBlock(15, 31, 15, 0x1A4)
+	assert.Equal(t, 0x1A4, col.Block(15, 31, 15))
+	assert.Equal(t, byte(0x1), col.Sections[1].Add.Get(4095))
+
+	// outside of the world
+	col.SetBlock(0, Height, 0, 1)
+	col.SetBlock(0, -1, 0, 1)
+	assert.Equal(t, Air, col.Block(0, Height, 0))
+	assert.Equal(t, Air, col.Block(0, -1, 0))
+
+	col.SetBiome(-1, 3, 4)
+	assert.Equal(t, byte(4), col.Biome(15, 3))
+	assert.Equal(t, byte(4), col.Biomes[3<<4|15])
+}
+
+func TestColumnData(t *testing.T) {
+	col := NewColumn(0, 0)
+	col.SetBlock(0, 0, 0, 7)
+	col.SetBlock(0, 40, 0, 0x101)
+	col.SetMetadata(0, 40, 0, 5)
+	col.SetBiome(0, 0, 1)
+	// a section with only air in it is not sent
+	col.SetBlock(0, 100, 0, 1)
+	col.SetBlock(0, 100, 0, Air)
+
+	data, primary, add := col.Data(true, true)
+	assert.Equal(t, uint16(0b101), primary)
+	assert.Equal(t, uint16(0b100), add)
+	assert.Len(t, data, 2*4096+2*2048+2*2048+2*2048+2048+256)
+	assert.Len(t, data, DataSize(primary, add, true, true))
+
+	const nibbles = 2048
+	blocks := data[:2*4096]
+	assert.Equal(t, byte(7), blocks[0])
+	assert.Equal(t, byte(1), blocks[4096+8<<8])
+	metadata := data[2*4096:]
+	assert.Equal(t, byte(5), metadata[nibbles+8<<7])
+	skyLight := data[2*4096+4*nibbles:]
+	assert.Equal(t, byte(0xFF), skyLight[0])
+	addArrays := data[2*4096+6*nibbles:]
+	assert.Equal(t, byte(1), addArrays[8<<7])
+	biomes := data[2*4096+7*nibbles:]
+	assert.Equal(t, byte(1), biomes[0])
+
+	data, _, _ = col.Data(false, false)
+	assert.Len(t, data, 2*4096+2*2048+2*2048+2048)
+
+	data, primary, add = NewColumn(0, 0).Data(true, true)
+	assert.Len(t, data, 256)
+	assert.Zero(t, primary)
+	assert.Zero(t, add)
+}
+
+func TestChunkPosAt(t *testing.T) {
+	assert.Equal(t, ChunkPos{X: 0, Z: 0}, ChunkPosAt(0.5, 15.9))
+	assert.Equal(t, ChunkPos{X: -1, Z: 1}, ChunkPosAt(-0.5, 16))
+	assert.Equal(t, ChunkPos{X: -2, Z: -1}, ChunkPosAt(-17, -16))
+	assert.Equal(t, ChunkPos{X: 2, Z: -1}, NewColumn(2, -1).Pos())
+}